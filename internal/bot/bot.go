@@ -22,7 +22,24 @@ func New(token string) (*Bot, error) {
 		{Command: "start", Description: "главное меню"},
 		{Command: "help", Description: "справка"},
 		{Command: "upload_report", Description: "загрузить данные"},
+		{Command: "merge", Description: "дополнить данные из файла"},
 		{Command: "periods", Description: "показать периоды"},
+		{Command: "crossings", Description: "пересечения границ"},
+		{Command: "rule", Description: "правило расчёта"},
+		{Command: "daypolicy", Description: "как считать дни переезда"},
+		{Command: "tiebreak", Description: "резидентство по налоговому соглашению"},
+		{Command: "state", Description: "вопросы для правил штатов США"},
+		{Command: "gaps", Description: "как учитывать пробелы между периодами"},
+		{Command: "fillgaps", Description: "заполнить пробелы между периодами"},
+		{Command: "normalize", Description: "упорядочить периоды"},
+		{Command: "report", Description: "отчёт"},
+		{Command: "export", Description: "выгрузить периоды в CSV"},
+		{Command: "uk", Description: "вопросы для теста UK"},
+		{Command: "schengen", Description: "шенген 90/180"},
+		{Command: "taxyear", Description: "отчёт за налоговый год"},
+		{Command: "years", Description: "статус по годам"},
+		{Command: "timeline", Description: "даты изменения статуса"},
+		{Command: "plan", Description: "планирование поездок"},
 		{Command: "reset", Description: "сбросить данные"},
 		{Command: "commands", Description: "список команд"},
 	}
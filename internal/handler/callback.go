@@ -5,10 +5,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"telegram-tax-bot/internal/importer"
 	"telegram-tax-bot/internal/keyboard"
 	"telegram-tax-bot/internal/manager"
 	"telegram-tax-bot/internal/model"
 	"telegram-tax-bot/internal/utils"
+	"telegram-tax-bot/internal/validator"
+	"time"
 
 	reportbuilder "telegram-tax-bot/internal/report_builder"
 
@@ -108,6 +111,20 @@ func handleHelpCommand(msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 🔁 Другие функции:
 — /reset — сбросить все данные
 — /periods — показать список загруженных периодов
+— /merge — добавить периоды из нового файла к текущим, не теряя правок
+— /crossings — журнал пересечений границ: периоды строятся по въездам и выездам
+— выписка о пересечениях границы с Госуслуг (.xlsx, PDF не читается) — заполняет журнал пересечений
+— /export csv — выгрузить периоды в таблицу (CSV можно и загрузить обратно)
+— файл .ics из календаря — поездки из событий, где указана страна
+— Records.json из Google Takeout — периоды по истории местоположений (через /upload_report или /merge)
+— пересланный электронный билет — поездки между рейсами по кодам аэропортов
+— /rule — выбрать правило расчёта резидентства
+— /daypolicy — как считать дни переезда
+— /tiebreak [правило] — резидентство по налоговому соглашению: сравнивает выбранное правило с правилом второй страны
+— /state — вопросы о домицилии для правил штатов Нью-Йорк и Калифорния
+— /gaps — как учитывать дни между периодами
+— /fillgaps — заполнить пробелы по одному
+— /normalize — упорядочить периоды: сортировка, объединение, пересечения
 
 💬 Используйте /start для возврата в главное меню.`
 
@@ -120,7 +137,24 @@ func handleCommandsCommand(msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	txt := `/start - главное меню
 /help - справка
 /upload_report - загрузить данные
+/merge - дополнить данные из файла
 /periods - показать периоды
+/crossings - журнал пересечений границ
+/rule - выбрать правило расчёта
+/daypolicy - как считать дни переезда
+/tiebreak - резидентство по налоговому соглашению
+/state - вопросы для правил штатов США
+/gaps - как учитывать пробелы между периодами
+/fillgaps - заполнить пробелы между периодами
+/normalize - упорядочить периоды
+/uk [год] - вопросы для теста резидентства Великобритании за налоговый год
+/schengen [дней] - остаток по правилу 90/180 и дата въезда
+/taxyear <год> [страна] - отчёт за налоговый год
+/years [страна] - статус по всем годам
+/timeline - даты изменения статуса
+/plan <страна> [дата] - сколько дней осталось до порога
+/report - отчёт (/report json, /report csv — файлом)
+/export csv - выгрузить периоды в CSV
 /reset - сбросить данные`
 	newMsg := tgbotapi.NewMessage(msg.Chat.ID, txt)
 	newMsg.ReplyMarkup = keyboard.BuildBackToMenu()
@@ -135,6 +169,8 @@ func handleResetCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.B
 	s.Data = model.Data{}
 	s.Backup = model.Data{}
 	s.Temp = nil
+	s.PendingAction = ""
+	s.Step = 0
 	_ = os.Remove(fmt.Sprintf("%s/data.json", s.HistoryDir))
 	s.SaveSession()
 
@@ -155,9 +191,25 @@ func handleSetDateCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi
 
 func handleUploadCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	s.Data.Current = "upload_pending"
+	s.PendingAction = ""
+	s.SaveSession()
+
+	newMsg := tgbotapi.NewMessage(msg.Chat.ID, "📎 Пришлите JSON- или CSV-файл документом (столбцы: въезд, выезд, страна). Подойдёт и история местоположений из Google Takeout (Records.json).")
+	newMsg.ReplyMarkup = keyboard.BuildBackToMenu()
+	bot.Send(newMsg)
+}
+
+// handleMergeCommand waits for a file whose periods are added to the current
+// ones instead of replacing them.
+func handleMergeCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		handleUploadCommand(s, msg, bot)
+		return
+	}
+	s.PendingAction = "awaiting_merge_file"
 	s.SaveSession()
 
-	newMsg := tgbotapi.NewMessage(msg.Chat.ID, "📎 Пришлите JSON-файл документом.")
+	newMsg := tgbotapi.NewMessage(msg.Chat.ID, "📥 Пришлите JSON- или CSV-файл документом либо JSON текстом. Его периоды будут добавлены к текущим — перед применением бот покажет, что изменится.")
 	newMsg.ReplyMarkup = keyboard.BuildBackToMenu()
 	bot.Send(newMsg)
 }
@@ -168,11 +220,76 @@ func handlePeriodsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi
 		return
 	}
 	msgText := s.BuildPeriodsList()
+	if issues := validator.Render(validator.Validate(s.Data)); issues != "" {
+		msgText += "\n" + issues
+	}
+	if len(s.Data.Crossings) > 0 {
+		msgText += "\nℹ️ Периоды построены по пересечениям границ (/crossings). При правке периода напрямую журнал будет удалён, прежние данные сохранятся в резервной копии."
+	}
 	newMsg := tgbotapi.NewMessage(msg.Chat.ID, msgText)
 	newMsg.ReplyMarkup = keyboard.BuildPeriodsMenu()
 	bot.Send(newMsg)
 }
 
+// crossingsFormat explains how to type crossings.
+const crossingsFormat = "По одному на строку: «ДД.ММ.ГГГГ [ЧЧ:ММ] въезд|выезд Страна[, пункт пропуска]», например:\n01.02.2024 выезд из России, Шереметьево\n01.02.2024 въезд в Грузию"
+
+// handleCrossingsCommand shows the border crossing log and its problems.
+func handleCrossingsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	s.PendingAction = ""
+	s.SaveSession()
+	if len(s.Data.Crossings) == 0 {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "🛂 Журнал пересечений границ пуст. Если у вас есть официальный список въездов и выездов, внесите его — периоды будут построены по нему.\n\n"+crossingsFormat)
+		reply.ReplyMarkup = keyboard.BuildCrossingsMenu(false)
+		bot.Send(reply)
+		return
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString("🛂 Пересечения границ:\n")
+	for i, c := range s.Data.Crossings {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, importer.FormatCrossing(c)))
+	}
+	periods, issues := validator.DeriveCrossings(s.Data.Crossings)
+	builder.WriteString(fmt.Sprintf("\nПериодов по пересечениям: %d.\n", len(periods)))
+	if text := validator.Render(issues); text != "" {
+		builder.WriteString("\n" + text)
+	}
+	sendLong(msg.Chat.ID, builder.String(), keyboard.BuildCrossingsMenu(true), bot)
+}
+
+func handleAddCrossings(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	s.PendingAction = "awaiting_crossings_add"
+	s.SaveSession()
+	reply := tgbotapi.NewMessage(msg.Chat.ID, "➕ Введите пересечения. "+crossingsFormat)
+	reply.ReplyMarkup = keyboard.BuildBack()
+	bot.Send(reply)
+}
+
+func handleEditCrossing(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if len(s.Data.Crossings) == 0 {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 Нет пересечений для изменения."))
+		return
+	}
+	s.PendingAction = "awaiting_crossing_edit_index"
+	s.SaveSession()
+	reply := tgbotapi.NewMessage(msg.Chat.ID, "✏️ Введите номер пересечения:")
+	reply.ReplyMarkup = keyboard.BuildBack()
+	bot.Send(reply)
+}
+
+func handleDeleteCrossings(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if len(s.Data.Crossings) == 0 {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 Нет пересечений для удаления."))
+		return
+	}
+	s.PendingAction = "awaiting_crossings_delete"
+	s.SaveSession()
+	reply := tgbotapi.NewMessage(msg.Chat.ID, "🗑 Введите номера пересечений для удаления через запятую:")
+	reply.ReplyMarkup = keyboard.BuildBack()
+	bot.Send(reply)
+}
+
 func handleAddGapPeriod(s *model.Session, callback *tgbotapi.CallbackQuery, bot *tgbotapi.BotAPI) {
 	chatID := callback.Message.Chat.ID
 
@@ -189,6 +306,7 @@ func handleAddGapPeriod(s *model.Session, callback *tgbotapi.CallbackQuery, bot
 		Country: "unknown",
 	}
 
+	detachCrossings(s, callback.Message, bot)
 	// Вставить "unknown" перед текущим
 	s.Data.Periods = append(
 		s.Data.Periods[:s.EditingIndex],
@@ -219,6 +337,7 @@ func handleAdjustNextIn(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.B
 		return
 	}
 
+	detachCrossings(s, msg, bot)
 	// ✅ Обновляем out у текущего периода и in у следующего
 	s.Data.Periods[index].Out = s.TempEditedOut
 	s.Data.Periods[index+1].In = newOut.AddDate(0, 0, 1).Format("02.01.2006")
@@ -233,11 +352,13 @@ func handleAdjustNextIn(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.B
 
 func handleKeepConflict(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	if s.PendingAction == "confirm_conflict_in" {
+		detachCrossings(s, msg, bot)
 		s.Data.Periods[s.EditingIndex].In = s.TempEditedIn
 		s.PendingAction = ""
 		s.SaveSession()
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Дата въезда обновлена."))
 	} else if s.PendingAction == "confirm_conflict_out" {
+		detachCrossings(s, msg, bot)
 		s.Data.Periods[s.EditingIndex].Out = s.TempEditedOut
 		s.PendingAction = ""
 		s.SaveSession()
@@ -270,9 +391,11 @@ func handleBack(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 		s.PendingAction = ""
 		s.SaveSession()
 		handlePeriodsCommand(s, msg, bot)
+	case "awaiting_crossings_add", "awaiting_crossing_edit_index", "awaiting_crossing_edit", "awaiting_crossings_delete":
+		handleCrossingsCommand(s, msg, bot)
 	case "awaiting_edit_field":
 		handleEditPeriod(s, msg, bot)
-	case "awaiting_new_in", "awaiting_new_out", "awaiting_new_country":
+	case "awaiting_new_in", "awaiting_new_out", "awaiting_new_country", "awaiting_new_purpose", "awaiting_new_region", "awaiting_new_times":
 		s.PendingAction = "awaiting_edit_field"
 		s.SaveSession()
 		buttons := keyboard.BuildEditFieldMenu()
@@ -288,12 +411,599 @@ func handleBack(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 }
 
 func handleShowReport(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	sendReport(s, msg, bot, "")
+}
+
+// sendReport builds the report for the session and sends it; when the data
+// cannot be analysed the user is pointed to the period list instead.
+func sendReport(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI, header string) {
+	sendBuiltReport(reportbuilder.BuildReport(s.Data), msg, bot, header)
+}
+
+func sendBuiltReport(report reportbuilder.Report, msg *tgbotapi.Message, bot *tgbotapi.BotAPI, header string) {
+	if report.HasErrors() {
+		text := header + "⛔ Не удалось построить отчёт.\n" + reportbuilder.RenderText(report)
+		reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+		reply.ReplyMarkup = keyboard.BuildPeriodsMenu()
+		bot.Send(reply)
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, header+reportbuilder.RenderText(report))
+	reply.ReplyMarkup = keyboard.BuildReportMenu(report.UnknownDays > 0)
+	bot.Send(reply)
+}
+
+// handleTaxYearCommand evaluates a chosen fiscal year, e.g.
+// "/taxyear 2024 Великобритания", without changing the calculation date.
+func handleTaxYearCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+
+	args := strings.Fields(strings.TrimPrefix(msg.Text, "/taxyear"))
+	if len(args) == 0 {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Укажите год и, при необходимости, страну: /taxyear 2024 Великобритания"))
+		return
+	}
+	year, err := strconv.Atoi(args[0])
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Год должен быть числом, например: /taxyear 2024"))
+		return
+	}
+
+	calendar := reportbuilder.CalendarYear
+	if len(args) > 1 {
+		calendar = reportbuilder.CalendarFor(strings.Join(args[1:], " "))
+	}
+	from, to := calendar.Year(year)
+	// текущий год считается по дату расчёта: открытый период не должен
+	// дотягиваться до конца года будущими днями
+	calcDate := to
+	if current, err := utils.ParseDate(s.Data.Current); err == nil && current.Before(to) {
+		calcDate = current
+	}
+	if calcDate.Before(from) {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Налоговый год %s начинается %s — позже даты расчёта %s.", calendar.Label(from), utils.FormatDate(from), utils.FormatDate(calcDate))))
+		return
+	}
+	report := reportbuilder.BuildReportFor(s.Data, reportbuilder.FiscalYearRule(calendar), calcDate)
+	header := fmt.Sprintf("🗓 Налоговый год %s (%s)\n", calendar.Label(from), calendar.Name)
+	if calcDate.Before(to) {
+		header += fmt.Sprintf("⏳ Год ещё идёт: учтены дни по %s (дата расчёта), итог может измениться.\n", utils.FormatDate(calcDate))
+	}
+	sendBuiltReport(report, msg, bot, header+"\n")
+}
+
+// handleYearsCommand shows the residency status for every tax year in the
+// data; "/years Великобритания" uses that country's tax calendar.
+func handleYearsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+
+	rule := reportbuilder.RuleByName(s.Data.Rule)
+	calendar := reportbuilder.CalendarOf(rule)
+	if strings.HasPrefix(msg.Text, "/years") {
+		if country := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/years")); country != "" {
+			calendar = reportbuilder.CalendarFor(country)
+		}
+	}
+
+	rows, err := reportbuilder.BuildYearsReport(s.Data, rule, calendar)
+	if err != nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Не удалось построить отчёт.\nОшибка: %v", err))
+		reply.ReplyMarkup = keyboard.BuildPeriodsMenu()
+		bot.Send(reply)
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, reportbuilder.RenderYears(rows, rule))
+	reply.ReplyMarkup = keyboard.BuildBackToMenu()
+	bot.Send(reply)
+}
+
+func handleTimelineCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+
+	timeline, err := reportbuilder.BuildTimeline(s.Data, 183)
+	if err != nil {
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Не удалось построить отчёт.\nОшибка: %v", err))
+		reply.ReplyMarkup = keyboard.BuildPeriodsMenu()
+		bot.Send(reply)
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, reportbuilder.RenderTimeline(timeline))
+	reply.ReplyMarkup = keyboard.BuildBackToMenu()
+	bot.Send(reply)
+}
+
+// handlePlanCommand answers "/plan Грузия 31.12.2025": how many days are
+// left before becoming resident and how long to stay to keep residency.
+func handlePlanCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+
+	args := strings.Fields(strings.TrimPrefix(msg.Text, "/plan"))
+	if len(args) == 0 {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Укажите страну и дату: /plan Грузия 31.12.2025"))
+		return
+	}
+
+	current, err := utils.ParseDate(s.Data.Current)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Сначала задайте дату расчёта."))
+		return
+	}
+	target := time.Date(current.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+	if date, err := utils.ParseDate(args[len(args)-1]); err == nil {
+		target = date
+		args = args[:len(args)-1]
+	}
+	country := strings.Join(args, " ")
+	if country == "" {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Укажите страну: /plan Грузия 31.12.2025"))
+		return
+	}
+
+	forecast, err := reportbuilder.BuildForecast(s.Data, country, target, 183)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка: %v", err)))
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, reportbuilder.RenderForecast(forecast))
+	reply.ReplyMarkup = keyboard.BuildBackToMenu()
+	bot.Send(reply)
+}
+
+// handleExportReport sends the report as a JSON or CSV document.
+func handleExportReport(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI, format string) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+
 	report := reportbuilder.BuildReport(s.Data)
-	reply := tgbotapi.NewMessage(msg.Chat.ID, report)
+	var (
+		body []byte
+		err  error
+	)
+	switch format {
+	case "json":
+		body, err = reportbuilder.RenderJSON(report)
+	case "csv":
+		body, err = reportbuilder.RenderCSV(report)
+	default:
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Поддерживаемые форматы: /report json, /report csv"))
+		return
+	}
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Не удалось сформировать файл отчёта."))
+		return
+	}
+
+	doc := tgbotapi.NewDocument(msg.Chat.ID, tgbotapi.FileBytes{Name: "report." + format, Bytes: body})
+	bot.Send(doc)
+}
+
+// handleExportCommand sends the current periods as a file, e.g. "/export csv".
+func handleExportCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI, format string) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+	if format != "csv" {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Поддерживаемые форматы: /export csv"))
+		return
+	}
+	body, err := importer.FormatCSV(s.Data.Periods)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Не удалось сформировать файл."))
+		return
+	}
+	doc := tgbotapi.NewDocument(msg.Chat.ID, tgbotapi.FileBytes{Name: "periods.csv", Bytes: body})
+	bot.Send(doc)
+}
+
+func handleRuleCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	current := reportbuilder.RuleByName(s.Data.Rule)
+	var titles []string
+	for _, rule := range reportbuilder.Rules() {
+		titles = append(titles, rule.Title())
+	}
+
+	s.PendingAction = "awaiting_rule"
+	s.SaveSession()
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⚖️ Текущее правило: %s\nВыберите правило расчёта:", current.Title()))
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu(titles)
+	bot.Send(reply)
+}
+
+// handleDayPolicyCommand lets the user choose how travel days are counted.
+func handleDayPolicyCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	rule := reportbuilder.RuleByName(s.Data.Rule)
+	current := reportbuilder.PolicyFor(s.Data, rule)
+	titles := []string{dayPolicyDefault}
+	for _, p := range reportbuilder.DayPolicies {
+		titles = append(titles, p.Title)
+	}
+
+	s.PendingAction = "awaiting_day_policy"
+	s.SaveSession()
+
+	text := fmt.Sprintf("✈️ День переезда сейчас засчитывается: %s\n", strings.ToLower(reportbuilder.DayPolicyTitle(current)))
+	if s.Data.DayPolicy == "" {
+		text += "Так принято в правиле «" + rule.Title() + "».\n"
+	}
+	text += "\nДень переезда — дата, которая одновременно выезд из одной страны и въезд в другую. Выберите, как его считать:"
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu(titles)
+	bot.Send(reply)
+}
+
+// handleGapsCommand lets the user choose how days between periods count.
+func handleGapsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	var titles []string
+	for _, g := range reportbuilder.GapStrategies {
+		titles = append(titles, g.Title)
+	}
+
+	s.PendingAction = "awaiting_gap_strategy"
+	s.SaveSession()
+
+	current := reportbuilder.GapStrategyOf(s.Data)
+	text := fmt.Sprintf("🕳 Дни между периодами сейчас: %s", strings.ToLower(reportbuilder.GapStrategyTitle(current)))
+	if current == reportbuilder.GapHome {
+		text += fmt.Sprintf(" (%s)", s.Data.HomeCountry)
+	}
+	text += "\n\nКак учитывать дни, о которых нет данных?"
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu(titles)
+	bot.Send(reply)
+}
+
+// handleFillGapsCommand walks through the unknown stretches one by one and
+// turns each answer into a period.
+func handleFillGapsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	gaps, err := reportbuilder.FindGaps(s.Data)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Сначала исправьте периоды: %s.", err)))
+		return
+	}
+	if len(gaps) == 0 {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ В периоде отчёта нет дней «неизвестно где»."))
+		return
+	}
+	s.BackupSession()
+	s.Step = 0
+	s.PendingAction = "awaiting_gap_country"
+	s.SaveSession()
+	askGapCountry(s, msg, bot)
+}
+
+// askGapCountry asks about the gap at s.Step or finishes the wizard.
+func askGapCountry(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	gaps, err := reportbuilder.FindGaps(s.Data)
+	if err != nil {
+		s.PendingAction = ""
+		s.Step = 0
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Сначала исправьте периоды: %s.", err)))
+		return
+	}
+	if s.Step >= len(gaps) {
+		s.PendingAction = ""
+		s.Step = 0
+		s.SaveSession()
+		header := "✅ Пробелы заполнены.\n\n"
+		if len(gaps) > 0 {
+			header = fmt.Sprintf("✅ Готово. Осталось пробелов: %d.\n\n", len(gaps))
+		}
+		sendReport(s, msg, bot, header)
+		return
+	}
+
+	gap := gaps[s.Step]
+	text := fmt.Sprintf("🕳 Пробел %d из %d: %s — %s (%d дней)\n", s.Step+1, len(gaps), utils.FormatDate(gap.From), utils.FormatDate(gap.To), gap.Days())
+	var options []string
+	if gap.Before != "" {
+		text += fmt.Sprintf("До этого: %s\n", gap.Before)
+		options = append(options, gap.Before)
+	}
+	if gap.After != "" {
+		text += fmt.Sprintf("После: %s\n", gap.After)
+		if gap.After != gap.Before {
+			options = append(options, gap.After)
+		}
+	}
+	text += "\nГде вы были? Выберите страну или введите её название."
+	options = append(options, gapSkip)
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu(options)
+	bot.Send(reply)
+}
+
+// handleNormalizeCommand previews the sorted and cleaned period list; the
+// overlap rule is asked only when some periods overlap.
+func handleNormalizeCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+	_, changes, err := validator.Normalize(s.Data, validator.KeepLater)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Сначала исправьте дату: %s.", err)))
+		return
+	}
+	for _, c := range changes {
+		if c.Kind == validator.Trimmed || c.Kind == validator.Split || c.Kind == validator.Dropped {
+			var titles []string
+			for _, r := range validator.OverlapRules {
+				titles = append(titles, r.Title)
+			}
+			s.PendingAction = "awaiting_overlap_rule"
+			s.SaveSession()
+			reply := tgbotapi.NewMessage(msg.Chat.ID, "✂️ Некоторые периоды пересекаются. Какой период оставить в пересечении?")
+			reply.ReplyMarkup = keyboard.BuildChoiceMenu(titles)
+			bot.Send(reply)
+			return
+		}
+	}
+	previewNormalized(s, msg, bot, validator.KeepLater)
+}
+
+// previewNormalized keeps the normalised periods in s.Temp until confirmed.
+func previewNormalized(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI, rule validator.OverlapRule) {
+	normalized, changes, err := validator.Normalize(s.Data, rule)
+	if err != nil {
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Сначала исправьте дату: %s.", err)))
+		return
+	}
+	if len(changes) == 0 {
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Периоды уже упорядочены."))
+		return
+	}
+
+	s.Temp = normalized.Periods
+	s.PendingAction = "awaiting_normalize_confirm"
+	s.SaveSession()
+
+	builder := strings.Builder{}
+	builder.WriteString("🧹 Предлагаемые изменения:\n")
+	for _, c := range changes {
+		builder.WriteString(fmt.Sprintf("• %s\n", c.Message))
+	}
+	preview := model.Session{Data: normalized}
+	builder.WriteString("\n" + preview.BuildPeriodsList())
+	if issues := validator.Render(validator.Validate(normalized)); issues != "" {
+		builder.WriteString("\n" + issues)
+	}
+	if len(s.Data.Crossings) > 0 {
+		builder.WriteString("\n⚠️ Сейчас периоды построены по журналу пересечений (/crossings). После применения журнал будет удалён, прежние данные сохранятся в резервной копии.\n")
+	}
+	builder.WriteString("\nПрименить изменения?")
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, builder.String())
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu([]string{normalizeApply, previewCancel})
+	bot.Send(reply)
+}
+
+// handleSchengenCommand shows the 90/180 balance on the calculation date;
+// "/schengen 14" asks for the earliest entry for a 14-day trip.
+func handleSchengenCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+
+	stay := 90
+	if strings.HasPrefix(msg.Text, "/schengen") {
+		if arg := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/schengen")); arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Укажите длительность поездки числом, например: /schengen 14"))
+				return
+			}
+			stay = n
+		}
+	}
+
+	date, err := utils.ParseDate(s.Data.Current)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Сначала задайте дату расчёта."))
+		return
+	}
+	status, err := reportbuilder.Schengen(s.Data, date)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка: %v", err)))
+		return
+	}
+	entry, err := reportbuilder.SchengenEntryDate(s.Data, date, stay)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ %v", err)))
+		return
+	}
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, reportbuilder.RenderSchengen(status, stay, entry))
 	reply.ReplyMarkup = keyboard.BuildBackToMenu()
 	bot.Send(reply)
 }
 
+// handleUKQuestionsCommand asks the SRT questions for the tax year
+// containing the calculation date; "/uk 2024" asks about 2023/24.
+func handleUKQuestionsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	calendar := reportbuilder.CalendarOf(reportbuilder.RuleByName("uk_srt"))
+	var from time.Time
+	if arg := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/uk")); arg != "" {
+		year, err := strconv.Atoi(arg)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Год должен быть числом, например: /uk 2024 (налоговый год 2023/24)"))
+			return
+		}
+		from, _ = calendar.Year(year)
+	} else {
+		date, err := utils.ParseDate(s.Data.Current)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Сначала укажите дату расчёта или налоговый год: /uk 2024"))
+			return
+		}
+		from, _ = calendar.YearContaining(date)
+	}
+
+	if s.Data.Answers == nil {
+		s.Data.Answers = map[string]string{}
+	}
+	s.Step = 0
+	s.TaxYear = utils.FormatDate(from)
+	s.PendingAction = "awaiting_uk_answer"
+	s.SaveSession()
+	askQuestion(s, msg, bot, ukQuestionsIcon(from), reportbuilder.UKQuestionsFor(from))
+}
+
+// ukQuestionsIcon heads the SRT questions with the tax year they are about.
+func ukQuestionsIcon(from time.Time) string {
+	return fmt.Sprintf("🇬🇧 Налоговый год %s.", reportbuilder.CalendarOf(reportbuilder.RuleByName("uk_srt")).Label(from))
+}
+
+// handleStateQuestionsCommand asks the questions of the US state rules.
+func handleStateQuestionsCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.Data.Answers == nil {
+		s.Data.Answers = map[string]string{}
+	}
+	s.Step = 0
+	s.PendingAction = "awaiting_state_answer"
+	s.SaveSession()
+	askQuestion(s, msg, bot, "🇺🇸", reportbuilder.StateQuestions)
+}
+
+// askQuestion asks the yes/no question at s.Step.
+func askQuestion(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI, icon string, questions []reportbuilder.Question) {
+	q := questions[s.Step]
+	text := fmt.Sprintf("%s Вопрос %d из %d\n\n%s", icon, s.Step+1, len(questions), q.Text)
+	switch s.Data.Answers[q.Key] {
+	case reportbuilder.AnswerYes:
+		text += "\n\nТекущий ответ: да"
+	case reportbuilder.AnswerNo:
+		text += "\n\nТекущий ответ: нет"
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildAnswerMenu()
+	bot.Send(reply)
+}
+
+// handleTieBreakCommand starts the treaty tie-breaker when the chosen rule
+// and the rule of the second jurisdiction, given as "/tiebreak <rule>",
+// find residence in two countries.
+func handleTieBreakCommand(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if s.IsEmpty() {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 У вас пока нет сохранённых периодов."))
+		return
+	}
+	date, err := utils.ParseDate(s.Data.Current)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Сначала задайте дату расчёта."))
+		return
+	}
+
+	if arg := strings.TrimSpace(strings.TrimPrefix(msg.Text, "/tiebreak")); arg != "" {
+		switch {
+		case arg == "off":
+			s.Data.SecondRule = ""
+		case isRuleName(arg):
+			s.Data.SecondRule = arg
+		default:
+			bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Неизвестное правило. "+secondRuleHint()))
+			return
+		}
+		s.SaveSession()
+	}
+
+	countries := reportbuilder.DualResidence(s.Data, reportbuilder.RuleByName(s.Data.Rule), date)
+	if len(countries) < 2 {
+		text := "✅ На дату расчёта вы резидент не более чем одной страны — определять резидентство по соглашению не нужно."
+		if len(reportbuilder.ComparedRules(s.Data, reportbuilder.RuleByName(s.Data.Rule))) < 2 {
+			text = "ℹ️ Сравнивать не с чем: выбрано одно правило. " + secondRuleHint()
+		}
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, text))
+		return
+	}
+
+	if s.Data.Answers == nil {
+		s.Data.Answers = map[string]string{}
+	}
+	for _, q := range reportbuilder.TieBreakQuestions {
+		delete(s.Data.Answers, q.Key)
+	}
+	s.Data.Answers[reportbuilder.TieBreakPair] = reportbuilder.PairKey(countries[:2])
+	s.PendingAction = "awaiting_tiebreak_answer"
+	s.SaveSession()
+
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("🤝 Вы резидент двух стран: %s и %s. Ответьте на вопросы ст. 4 соглашения об избежании двойного налогообложения.", countries[0], countries[1])))
+	askTieBreakQuestion(s, msg, bot)
+}
+
+// secondRuleHint explains how to name the rule of the second country.
+func secondRuleHint() string {
+	builder := strings.Builder{}
+	builder.WriteString("Укажите правило второй страны: /tiebreak <правило> (отключить — /tiebreak off).\n")
+	for _, rule := range reportbuilder.Rules() {
+		builder.WriteString(fmt.Sprintf("\n%s — %s", rule.Name(), rule.Title()))
+	}
+	return builder.String()
+}
+
+func isRuleName(name string) bool {
+	for _, rule := range reportbuilder.Rules() {
+		if rule.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// askTieBreakQuestion asks the next tie-breaker question or, when the
+// tie-breaker is complete, shows the report with the conclusion.
+func askTieBreakQuestion(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	date, _ := utils.ParseDate(s.Data.Current)
+	countries := strings.Split(s.Data.Answers[reportbuilder.TieBreakPair], ";")
+	tb := reportbuilder.ResolveTieBreak(s.Data, countries, date)
+	if tb.Next == "" {
+		s.PendingAction = ""
+		s.SaveSession()
+		sendReport(s, msg, bot, "✅ Ответы сохранены.\n\n")
+		return
+	}
+
+	text := ""
+	for _, q := range reportbuilder.TieBreakQuestions {
+		if q.Key == tb.Next {
+			text = q.Text
+		}
+	}
+	var titles []string
+	for _, o := range reportbuilder.TieBreakOptions(tb.Next, countries) {
+		titles = append(titles, o.Title)
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, "❓ "+text)
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu(titles)
+	bot.Send(reply)
+}
+
 func handleAddPeriod(msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	// меню выбора варианта добавления
 	reply := tgbotapi.NewMessage(msg.Chat.ID, "➕ Что добавить?")
@@ -346,6 +1056,7 @@ func handleEditPeriod(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.Bot
 func handleAdjustPrevOut(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	newIn, _ := utils.ParseDate(s.TempEditedIn)
 
+	detachCrossings(s, msg, bot)
 	s.Data.Periods[s.EditingIndex-1].Out = newIn.Format("02.01.2006")
 	s.Data.Periods[s.EditingIndex].In = newIn.Format("02.01.2006")
 	s.PendingAction = ""
@@ -382,6 +1093,48 @@ func handleEditCountry(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.Bo
 	bot.Send(reply)
 }
 
+func handleEditPurpose(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	s.PendingAction = "awaiting_new_purpose"
+	s.SaveSession()
+	titles := []string{purposeNone}
+	for _, p := range model.Purposes {
+		titles = append(titles, p.Title)
+	}
+	text := "🏷 Выберите цель поездки. Для лечения, обучения, работы на шельфе и форс-мажора правило расчёта может не учитывать дни или засчитать их другой стране:"
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildPurposeMenu(titles)
+	bot.Send(reply)
+}
+
+func handleEditRegion(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	s.PendingAction = "awaiting_new_region"
+	s.SaveSession()
+	reply := tgbotapi.NewMessage(msg.Chat.ID, "📍 Введите штат, провинцию или кантон (например, Нью-Йорк или California). Чтобы убрать регион, отправьте «-»:")
+	reply.ReplyMarkup = keyboard.BuildBack()
+	bot.Send(reply)
+}
+
+func handleEditTimes(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	s.PendingAction = "awaiting_new_times"
+	s.SaveSession()
+	p := s.Data.Periods[s.EditingIndex]
+	text := "🕐 Введите время въезда, время выезда и часовой пояс через пробел, например: 05:00 23:30 Europe/Moscow\n" +
+		"Поставьте «-» вместо значения, которое нужно убрать. Время нужно для правила «где вы в полночь» и перелётов через линию перемены дат."
+	if p.InTime != "" || p.OutTime != "" || p.TimeZone != "" {
+		text += fmt.Sprintf("\n\nСейчас: въезд %s, выезд %s, пояс %s", orDash(p.InTime), orDash(p.OutTime), orDash(p.TimeZone))
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildBack()
+	bot.Send(reply)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
 func handleDeletePeriod(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	if s.IsEmpty() {
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📭 Нет сохранённых периодов для удаления."))
@@ -404,6 +1157,7 @@ func handleAwaitingDeleteIndex(msg *tgbotapi.Message, s *model.Session, bot *tgb
 		return
 	}
 
+	detachCrossings(s, msg, bot)
 	idx := index - 1
 	s.Data.Periods = append(s.Data.Periods[:idx], s.Data.Periods[idx+1:]...)
 	s.PendingAction = ""
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"telegram-tax-bot/internal/importer"
 	"telegram-tax-bot/internal/keyboard"
 	"telegram-tax-bot/internal/manager"
 	"telegram-tax-bot/internal/model"
 	"telegram-tax-bot/internal/utils"
+	"telegram-tax-bot/internal/validator"
 	"time"
 
 	reportbuilder "telegram-tax-bot/internal/report_builder"
@@ -24,10 +27,26 @@ func (r *Registry) handleMessage(msg *tgbotapi.Message) {
 	text := msg.Text
 
 	// ✅ Загрузка JSON-файла
+	if msg.Document != nil && isICS(msg.Document) {
+		handleICSFile(msg, s, r.bot)
+		return
+	}
+	if msg.Document != nil && isXLSX(msg.Document) {
+		handleStatementFile(msg, s, r.bot)
+		return
+	}
+	if msg.Document != nil && isPDF(msg.Document) {
+		r.bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ PDF не поддерживается: бот читает выписку о пересечениях границы с Госуслуг только в формате Excel (.xlsx), остальные данные — JSON или CSV. Выписку можно также переписать вручную через /crossings."))
+		return
+	}
 	if msg.Document != nil && s.Data.Current == "upload_pending" {
 		handleInputFile(msg, s, r.bot)
 		return
 	}
+	if msg.Document != nil && s.PendingAction == "awaiting_merge_file" {
+		handleMergeFile(msg, s, r.bot)
+		return
+	}
 
 	// ✅ Команды и кнопки имеют приоритет над ожидаемыми действиями
 	switch {
@@ -46,6 +65,9 @@ func (r *Registry) handleMessage(msg *tgbotapi.Message) {
 	case text == "📎 Загрузить файл", text == "📎 Загрузить новый файл":
 		handleUploadCommand(s, msg, r.bot)
 		return
+	case strings.HasPrefix(text, "/merge"), text == "📥 Дополнить из файла":
+		handleMergeCommand(s, msg, r.bot)
+		return
 	case strings.HasPrefix(text, "/upload_report"):
 		handleUploadCommand(s, msg, r.bot)
 		return
@@ -64,6 +86,67 @@ func (r *Registry) handleMessage(msg *tgbotapi.Message) {
 	case text == "📊 Отчёт":
 		handleShowReport(s, msg, r.bot)
 		return
+	case strings.HasPrefix(text, "/export"):
+		handleExportCommand(s, msg, r.bot, strings.ToLower(strings.TrimSpace(strings.TrimPrefix(text, "/export"))))
+		return
+	case strings.HasPrefix(text, "/report"):
+		if format := strings.TrimSpace(strings.TrimPrefix(text, "/report")); format != "" {
+			handleExportReport(s, msg, r.bot, strings.ToLower(format))
+		} else {
+			handleShowReport(s, msg, r.bot)
+		}
+		return
+	case strings.HasPrefix(text, "/schengen"), text == "🇪🇺 Шенген 90/180":
+		handleSchengenCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/years"), text == "📆 Статус по годам":
+		handleYearsCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/plan"):
+		handlePlanCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/timeline"):
+		handleTimelineCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/taxyear"):
+		handleTaxYearCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/uk"):
+		handleUKQuestionsCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/fillgaps"), text == "🧩 Заполнить пробелы":
+		handleFillGapsCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/normalize"), text == "🧹 Упорядочить периоды":
+		handleNormalizeCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/gaps"):
+		handleGapsCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/state"):
+		handleStateQuestionsCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/tiebreak"):
+		handleTieBreakCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/daypolicy"):
+		handleDayPolicyCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/rule"), text == "⚖️ Правило расчёта":
+		handleRuleCommand(s, msg, r.bot)
+		return
+	case strings.HasPrefix(text, "/crossings"), text == "🛂 Пересечения границ":
+		handleCrossingsCommand(s, msg, r.bot)
+		return
+	case text == "➕ Добавить пересечения":
+		handleAddCrossings(s, msg, r.bot)
+		return
+	case text == "✏️ Изменить пересечение":
+		handleEditCrossing(s, msg, r.bot)
+		return
+	case text == "🗑 Удалить пересечения":
+		handleDeleteCrossings(s, msg, r.bot)
+		return
 	case text == "✏️ Отредактировать период":
 		handleEditPeriod(s, msg, r.bot)
 		return
@@ -82,6 +165,15 @@ func (r *Registry) handleMessage(msg *tgbotapi.Message) {
 	case text == "🌍 Изменить страну":
 		handleEditCountry(s, msg, r.bot)
 		return
+	case text == "🕐 Изменить время и часовой пояс":
+		handleEditTimes(s, msg, r.bot)
+		return
+	case text == "📍 Изменить регион":
+		handleEditRegion(s, msg, r.bot)
+		return
+	case text == "🏷 Изменить цель поездки":
+		handleEditPurpose(s, msg, r.bot)
+		return
 	case text == "🗓 Хвостовой (только выезд)":
 		handleAddTail(s, msg, r.bot)
 		return
@@ -126,6 +218,15 @@ func (r *Registry) handleMessage(msg *tgbotapi.Message) {
 	case "awaiting_new_out":
 		handleAwaitingNewOut(msg, s, r.bot)
 		return
+	case "awaiting_new_times":
+		handleAwaitingNewTimes(msg, s, r.bot)
+		return
+	case "awaiting_new_region":
+		handleAwaitingNewRegion(msg, s, r.bot)
+		return
+	case "awaiting_new_purpose":
+		handleAwaitingNewPurpose(msg, s, r.bot)
+		return
 	case "awaiting_new_country":
 		handleAwaitingNewCountry(msg, s, r.bot)
 		return
@@ -156,10 +257,69 @@ func (r *Registry) handleMessage(msg *tgbotapi.Message) {
 	case "awaiting_add_in":
 		handleAddin(msg, s, r.bot)
 		return
+	case "awaiting_rule":
+		handleAwaitingRule(msg, s, r.bot)
+		return
+	case "awaiting_day_policy":
+		handleAwaitingDayPolicy(msg, s, r.bot)
+		return
+	case "awaiting_tiebreak_answer":
+		handleAwaitingTieBreakAnswer(msg, s, r.bot)
+		return
+	case "awaiting_gap_country":
+		handleAwaitingGapCountry(msg, s, r.bot)
+		return
+	case "awaiting_merge_file":
+		handleMergeInput(text, msg, s, r.bot)
+		return
+	case "awaiting_crossings_add":
+		handleAwaitingCrossingsAdd(msg, s, r.bot)
+		return
+	case "awaiting_crossing_edit_index":
+		handleAwaitingCrossingEditIndex(msg, s, r.bot)
+		return
+	case "awaiting_crossing_edit":
+		handleAwaitingCrossingEdit(msg, s, r.bot)
+		return
+	case "awaiting_crossings_delete":
+		handleAwaitingCrossingsDelete(msg, s, r.bot)
+		return
+	case "awaiting_statement_review":
+		handleAwaitingStatementReview(msg, s, r.bot)
+		return
+	case "awaiting_takeout_review":
+		handleAwaitingTakeoutReview(msg, s, r.bot)
+		return
+	case "awaiting_ics_review", "awaiting_ticket_review":
+		handleAwaitingTripsReview(msg, s, r.bot)
+		return
+	case "awaiting_merge_confirm":
+		handleAwaitingMergeConfirm(msg, s, r.bot)
+		return
+	case "awaiting_overlap_rule":
+		handleAwaitingOverlapRule(msg, s, r.bot)
+		return
+	case "awaiting_normalize_confirm":
+		handleAwaitingNormalizeConfirm(msg, s, r.bot)
+		return
+	case "awaiting_gap_strategy":
+		handleAwaitingGapStrategy(msg, s, r.bot)
+		return
+	case "awaiting_home_country":
+		handleAwaitingHomeCountry(msg, s, r.bot)
+		return
+	case "awaiting_state_answer":
+		handleAwaitingStateAnswer(msg, s, r.bot)
+		return
+	case "awaiting_uk_answer":
+		handleAwaitingUKAnswer(msg, s, r.bot)
+		return
 	}
 
 	if strings.HasPrefix(text, "{") {
 		handleJSONInput(msg, s, r.bot)
+	} else if itinerary, err := importer.ParseItinerary(text, time.Now()); err == nil {
+		handleItinerary(itinerary, msg, s, r.bot)
 	} else {
 		r.bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "❓ Неизвестная команда. Введите /help, чтобы посмотреть список."))
 	}
@@ -195,8 +355,7 @@ func handleAwaitingDate(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.B
 	s.Data.Current = date.Format("02.01.2006")
 	s.PendingAction = ""
 	s.SaveSession()
-	report := reportbuilder.BuildReport(s.Data)
-	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Дата расчета установлена: %s\n\n%s", s.Data.Current, report)))
+	sendReport(s, msg, bot, fmt.Sprintf("✅ Дата расчета установлена: %s\n\n", s.Data.Current))
 }
 
 func handleAwaitingNewIn(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
@@ -259,6 +418,7 @@ func handleAwaitingNewIn(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.
 	}
 
 	// Всё в порядке, обновляем
+	detachCrossings(s, msg, bot)
 	s.Data.Periods[index].In = newDate.Format("02.01.2006")
 	s.PendingAction = ""
 	s.SaveSession()
@@ -324,6 +484,7 @@ func handleAwaitingNewOut(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi
 	}
 
 	// Всё в порядке, обновляем
+	detachCrossings(s, msg, bot)
 	s.Data.Periods[index].Out = newDate.Format("02.01.2006")
 	s.PendingAction = ""
 	s.SaveSession()
@@ -337,6 +498,7 @@ func handleAwaitingNewCountry(msg *tgbotapi.Message, s *model.Session, bot *tgbo
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Название страны не может быть пустым."))
 		return
 	}
+	detachCrossings(s, msg, bot)
 	s.Data.Periods[s.EditingIndex].Country = newCountry
 	s.PendingAction = ""
 	s.SaveSession()
@@ -344,6 +506,87 @@ func handleAwaitingNewCountry(msg *tgbotapi.Message, s *model.Session, bot *tgbo
 	handlePeriodsCommand(s, msg, bot)
 }
 
+func handleAwaitingNewRegion(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	region := strings.TrimSpace(msg.Text)
+	if region == "" {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Название региона не может быть пустым."))
+		return
+	}
+	detachCrossings(s, msg, bot)
+	period := &s.Data.Periods[s.EditingIndex]
+	if region == "-" {
+		period.Region = ""
+	} else {
+		period.Region = utils.RegionName(period.Country, region)
+	}
+	s.PendingAction = ""
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Регион обновлён."))
+	handlePeriodsCommand(s, msg, bot)
+}
+
+func handleAwaitingNewTimes(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	fields := strings.Fields(msg.Text)
+	if len(fields) == 0 || len(fields) > 3 {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Введите до трёх значений: время въезда, время выезда и часовой пояс."))
+		return
+	}
+	for len(fields) < 3 {
+		fields = append(fields, "-")
+	}
+	for i := range fields {
+		if fields[i] == "-" {
+			fields[i] = ""
+		}
+	}
+
+	p := s.Data.Periods[s.EditingIndex]
+	p.InTime, p.OutTime, p.TimeZone = fields[0], fields[1], fields[2]
+	for _, check := range []struct{ date, clock string }{{p.In, p.InTime}, {p.Out, p.OutTime}} {
+		if check.clock == "" {
+			continue
+		}
+		date := check.date
+		if date == "" {
+			date = "01.01.2000"
+		}
+		if _, err := utils.ParseDateTime(date, check.clock, p.TimeZone); err != nil {
+			bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Неверное время (ЧЧ:ММ) или часовой пояс (например, Europe/Moscow)."))
+			return
+		}
+	}
+
+	detachCrossings(s, msg, bot)
+	s.Data.Periods[s.EditingIndex] = p
+	s.PendingAction = ""
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Время обновлено."))
+	handlePeriodsCommand(s, msg, bot)
+}
+
+// purposeNone clears the purpose of a period.
+const purposeNone = "➖ Без особой цели"
+
+func handleAwaitingNewPurpose(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	title := strings.TrimSpace(msg.Text)
+	purpose, ok := "", title == purposeNone
+	for _, p := range model.Purposes {
+		if p.Title == title {
+			purpose, ok = p.Purpose, true
+		}
+	}
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите цель из списка."))
+		return
+	}
+	detachCrossings(s, msg, bot)
+	s.Data.Periods[s.EditingIndex].Purpose = purpose
+	s.PendingAction = ""
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Цель поездки обновлена."))
+	handlePeriodsCommand(s, msg, bot)
+}
+
 func handleAwaitingAddOut(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
 	if len(s.Temp) == 0 {
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⚠️ Внутренняя ошибка. Начните добавление заново."))
@@ -402,6 +645,7 @@ func handleAwaitingAddCountry(msg *tgbotapi.Message, s *model.Session, bot *tgbo
 		}
 	}
 
+	detachCrossings(s, msg, bot)
 	s.Data.Periods = append(s.Data.Periods, period)
 	s.Temp = nil
 	s.PendingAction = ""
@@ -417,6 +661,7 @@ func handleAddOpenCountry(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Название страны не может быть пустым"))
 		return
 	}
+	detachCrossings(s, msg, bot)
 	s.Data.Periods = append(s.Data.Periods, model.Period{
 		In:      s.Data.Current,
 		Out:     "",
@@ -472,6 +717,7 @@ func handleAwaitingTailCountry(msg *tgbotapi.Message, s *model.Session, bot *tgb
 		}
 	}
 
+	detachCrossings(s, msg, bot)
 	s.Data.Periods = append([]model.Period{period}, s.Data.Periods...)
 	s.PendingAction = ""
 	s.Temp = nil
@@ -526,6 +772,7 @@ func handleAwaitingHeadCountry(msg *tgbotapi.Message, s *model.Session, bot *tgb
 		}
 	}
 
+	detachCrossings(s, msg, bot)
 	s.Data.Periods = append(s.Data.Periods, period)
 	s.PendingAction = ""
 	s.Temp = nil
@@ -548,36 +795,908 @@ func handleAddin(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI)
 	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "📆 Введите дату выезда (ДД.ММ.ГГГГ):"))
 }
 
+func handleAwaitingRule(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	title := strings.TrimSpace(msg.Text)
+	for _, rule := range reportbuilder.Rules() {
+		if rule.Title() != title {
+			continue
+		}
+		s.Data.Rule = rule.Name()
+		s.PendingAction = ""
+		s.SaveSession()
+
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Правило расчёта: %s", rule.Title()))
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите правило из списка."))
+}
+
+// dayPolicyDefault resets the day policy to the one of the chosen rule.
+const dayPolicyDefault = "⚖️ Как в правиле расчёта"
+
+func handleAwaitingDayPolicy(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	title := strings.TrimSpace(msg.Text)
+	if title == dayPolicyDefault {
+		s.Data.DayPolicy = ""
+		s.PendingAction = ""
+		s.SaveSession()
+
+		policy := reportbuilder.PolicyFor(s.Data, reportbuilder.RuleByName(s.Data.Rule))
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Дни переезда считаются по правилу расчёта: %s", strings.ToLower(reportbuilder.DayPolicyTitle(policy))))
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+	for _, p := range reportbuilder.DayPolicies {
+		if p.Title != title {
+			continue
+		}
+		s.Data.DayPolicy = string(p.Policy)
+		s.PendingAction = ""
+		s.SaveSession()
+
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ День переезда засчитывается: %s", strings.ToLower(p.Title)))
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите вариант из списка."))
+}
+
+// gapSkip leaves the current gap unknown in the gap-filling wizard.
+const gapSkip = "⏭ Пропустить"
+
+func handleAwaitingGapCountry(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	country := strings.TrimSpace(msg.Text)
+	if country == "" {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Название страны не может быть пустым."))
+		return
+	}
+	if country == gapSkip {
+		s.Step++
+		s.SaveSession()
+		askGapCountry(s, msg, bot)
+		return
+	}
+
+	gaps, err := reportbuilder.FindGaps(s.Data)
+	if err != nil || s.Step >= len(gaps) {
+		askGapCountry(s, msg, bot)
+		return
+	}
+	// заполненный пробел исчезает из списка, поэтому Step не меняется
+	detachCrossings(s, msg, bot)
+	s.Data = reportbuilder.FillGap(s.Data, gaps[s.Step], country)
+	s.SaveSession()
+	askGapCountry(s, msg, bot)
+}
+
+func handleAwaitingGapStrategy(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	title := strings.TrimSpace(msg.Text)
+	for _, g := range reportbuilder.GapStrategies {
+		if g.Title != title {
+			continue
+		}
+		if g.Strategy == reportbuilder.GapHome {
+			s.PendingAction = "awaiting_home_country"
+			s.SaveSession()
+			text := "🏠 Введите домашнюю страну:"
+			if s.Data.HomeCountry != "" {
+				text = fmt.Sprintf("🏠 Введите домашнюю страну (сейчас: %s):", s.Data.HomeCountry)
+			}
+			reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+			reply.ReplyMarkup = keyboard.BuildBackToMenu()
+			bot.Send(reply)
+			return
+		}
+		s.Data.GapStrategy = string(g.Strategy)
+		s.PendingAction = ""
+		s.SaveSession()
+		sendReport(s, msg, bot, fmt.Sprintf("✅ Дни между периодами: %s\n\n", strings.ToLower(g.Title)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите вариант из списка."))
+}
+
+func handleAwaitingHomeCountry(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	country := strings.TrimSpace(msg.Text)
+	if country == "" {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Название страны не может быть пустым."))
+		return
+	}
+	s.Data.HomeCountry = country
+	s.Data.GapStrategy = string(reportbuilder.GapHome)
+	s.PendingAction = ""
+	s.SaveSession()
+	sendReport(s, msg, bot, fmt.Sprintf("✅ Дни между периодами засчитываются домашней стране: %s\n\n", country))
+}
+
+// Answers to the normalisation preview.
+const (
+	normalizeApply = "✅ Применить"
+	// previewCancel discards any previewed change.
+	previewCancel = "🚫 Не применять"
+)
+
+func handleAwaitingOverlapRule(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	title := strings.TrimSpace(msg.Text)
+	for _, r := range validator.OverlapRules {
+		if r.Title == title {
+			previewNormalized(s, msg, bot, r.Rule)
+			return
+		}
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите вариант из списка."))
+}
+
+func handleAwaitingNormalizeConfirm(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	switch strings.TrimSpace(msg.Text) {
+	case normalizeApply:
+		s.BackupSession()
+		s.Data.Periods = s.Temp
+		s.Data.Crossings = nil
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Периоды упорядочены."))
+		handlePeriodsCommand(s, msg, bot)
+	case previewCancel:
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Изменения не применены."))
+		handlePeriodsCommand(s, msg, bot)
+	default:
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите вариант из списка."))
+	}
+}
+
+func handleAwaitingUKAnswer(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	from, err := utils.ParseDate(s.TaxYear)
+	if err != nil {
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⚠️ Внутренняя ошибка. Начните опрос заново: /uk"))
+		return
+	}
+	if !handleQuestionAnswer(msg, s, bot, ukQuestionsIcon(from), reportbuilder.UKQuestionsFor(from), "/uk") {
+		return
+	}
+	s.Data.Rule = "uk_srt"
+	s.SaveSession()
+	title := reportbuilder.RuleByName(s.Data.Rule).Title()
+	sendReport(s, msg, bot, fmt.Sprintf("✅ Ответы сохранены. Правило расчёта: %s\n\n", title))
+}
+
+func handleAwaitingStateAnswer(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	if !handleQuestionAnswer(msg, s, bot, "🇺🇸", reportbuilder.StateQuestions, "/state") {
+		return
+	}
+	header := "✅ Ответы сохранены.\n\n"
+	if s.Data.Rule != "us_ny" && s.Data.Rule != "us_ca" {
+		header = "✅ Ответы сохранены. Чтобы применить их, выберите правило штата: /rule\n\n"
+	}
+	sendReport(s, msg, bot, header)
+}
+
+// handleQuestionAnswer stores the answer to the question at s.Step and asks
+// the next one. It reports true once the last question is answered.
+func handleQuestionAnswer(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI, icon string, questions []reportbuilder.Question, restart string) bool {
+	if s.Step < 0 || s.Step >= len(questions) {
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⚠️ Внутренняя ошибка. Начните опрос заново: "+restart))
+		return false
+	}
+
+	key := questions[s.Step].Key
+	if s.Data.Answers == nil {
+		s.Data.Answers = map[string]string{}
+	}
+	switch strings.TrimSpace(msg.Text) {
+	case "✅ Да":
+		s.Data.Answers[key] = reportbuilder.AnswerYes
+	case "🚫 Нет":
+		s.Data.Answers[key] = reportbuilder.AnswerNo
+	case "⏭ Пропустить":
+		delete(s.Data.Answers, key)
+	default:
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите ответ кнопкой."))
+		return false
+	}
+
+	s.Step++
+	if s.Step < len(questions) {
+		s.SaveSession()
+		askQuestion(s, msg, bot, icon, questions)
+		return false
+	}
+
+	s.PendingAction = ""
+	s.Step = 0
+	s.SaveSession()
+	return true
+}
+
+func handleAwaitingTieBreakAnswer(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	date, err := utils.ParseDate(s.Data.Current)
+	if err != nil {
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Сначала задайте дату расчёта."))
+		return
+	}
+	countries := strings.Split(s.Data.Answers[reportbuilder.TieBreakPair], ";")
+	tb := reportbuilder.ResolveTieBreak(s.Data, countries, date)
+
+	title := strings.TrimSpace(msg.Text)
+	for _, o := range reportbuilder.TieBreakOptions(tb.Next, countries) {
+		if o.Title != title {
+			continue
+		}
+		if s.Data.Answers == nil {
+			s.Data.Answers = map[string]string{}
+		}
+		s.Data.Answers[tb.Next] = o.Answer
+		s.SaveSession()
+		askTieBreakQuestion(s, msg, bot)
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите ответ кнопкой."))
+}
+
 func handleJSONInput(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
 	s.BackupSession()
+	s.Data.Crossings = nil // журнал из файла заменяет прежний
 	err := json.Unmarshal([]byte(msg.Text), &s.Data)
 	if err != nil {
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Ошибка в формате JSON."))
 		return
 	}
+	if len(s.Data.Crossings) > 0 {
+		syncCrossings(s)
+	}
+	sendUploadResult(s, msg, bot)
+}
+
+// handleCSVInput replaces the periods with those from a spreadsheet export;
+// the other settings are kept.
+func handleCSVInput(body []byte, msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	periods, err := importer.ParseCSV(body)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка в CSV: %s.", err)))
+		return
+	}
+	s.BackupSession()
+	s.Data.Periods = periods
+	s.Data.Crossings = nil
+	sendUploadResult(s, msg, bot)
+}
+
+// sendUploadResult saves uploaded data, reports validation issues and sends
+// the report.
+func sendUploadResult(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
 	if s.Data.Current == "" {
 		s.Data.Current = time.Now().Format("02.01.2006")
 	}
 	s.SaveSession()
-	report := reportbuilder.BuildReport(s.Data)
-	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, report))
+	if issues := validator.Render(validator.Validate(s.Data)); issues != "" {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, issues+"\nИсправить периоды можно в разделе /periods, а упорядочить автоматически — командой /normalize."))
+	}
+	sendReport(s, msg, bot, "")
 }
 
-func handleInputFile(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
-	fileID := msg.Document.FileID
-	file, _ := bot.GetFile(tgbotapi.FileConfig{FileID: fileID})
-	url := file.Link(bot.Token)
-	resp, err := http.Get(url)
+// isCSV tells a spreadsheet export from a JSON upload.
+func isCSV(doc *tgbotapi.Document) bool {
+	return strings.HasSuffix(strings.ToLower(doc.FileName), ".csv") || doc.MimeType == "text/csv"
+}
 
+// downloadDocument fetches the file attached to the message.
+func downloadDocument(msg *tgbotapi.Message, bot *tgbotapi.BotAPI) ([]byte, error) {
+	file, err := bot.GetFile(tgbotapi.FileConfig{FileID: msg.Document.FileID})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Get(file.Link(bot.Token))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func handleInputFile(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	body, err := downloadDocument(msg, bot)
 	if err != nil {
 		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Не удалось загрузить файл."))
 		return
 	}
 
-	body, _ := io.ReadAll(resp.Body)
-	_ = resp.Body.Close()
-
-	msg.Text = string(body)
 	s.Data.Current = "" // сбрасываем флаг после загрузки
+	if importer.IsLocationHistory(body) {
+		handleLocationHistory(body, msg, s, bot)
+		return
+	}
+	if isCSV(msg.Document) {
+		handleCSVInput(body, msg, s, bot)
+		return
+	}
+	msg.Text = string(body)
 	handleJSONInput(msg, s, bot)
 }
+
+func handleMergeFile(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	body, err := downloadDocument(msg, bot)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Не удалось загрузить файл."))
+		return
+	}
+	if importer.IsLocationHistory(body) {
+		handleLocationHistory(body, msg, s, bot)
+		return
+	}
+	if isCSV(msg.Document) {
+		periods, err := importer.ParseCSV(body)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка в CSV: %s.", err)))
+			return
+		}
+		previewMerge(periods, msg, s, bot)
+		return
+	}
+	handleMergeInput(string(body), msg, s, bot)
+}
+
+func handleMergeInput(text string, msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	var incoming model.Data
+	if err := json.Unmarshal([]byte(text), &incoming); err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Ошибка в формате JSON."))
+		return
+	}
+	previewMerge(incoming.Periods, msg, s, bot)
+}
+
+// previewMerge compares the imported periods with the current ones and
+// keeps them in s.Temp until the user confirms the diff.
+func previewMerge(incoming []model.Period, msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	result, err := validator.Merge(s.Data.Periods, incoming)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Не удалось сравнить периоды: %s.", err)))
+		return
+	}
+	if result.IsEmpty() {
+		s.PendingAction = ""
+		s.SaveSession()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("✅ Новых периодов нет: все %d уже загружены.", len(result.Duplicates)))
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+
+	s.Temp = incoming
+	s.PendingAction = "awaiting_merge_confirm"
+	s.SaveSession()
+
+	options := []string{mergeApply}
+	if len(result.Conflicts) > 0 {
+		options = append(options, mergeApplyAll)
+	}
+	options = append(options, previewCancel)
+	text := result.Render() + "\nДобавить периоды к текущим?"
+	if len(result.Conflicts) > 0 {
+		text += " Конфликтующие периоды можно пропустить или добавить и затем упорядочить командой /normalize."
+	}
+	if len(s.Data.Crossings) > 0 {
+		text += "\n⚠️ Сейчас периоды построены по журналу пересечений (/crossings). После добавления журнал будет удалён, иначе его следующее изменение заменило бы добавленные периоды; прежние данные сохранятся в резервной копии."
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, text)
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu(options)
+	bot.Send(reply)
+}
+
+// isICS recognises iCalendar files, which are imported from any menu.
+func isICS(doc *tgbotapi.Document) bool {
+	return strings.HasSuffix(strings.ToLower(doc.FileName), ".ics") || doc.MimeType == "text/calendar"
+}
+
+// handleICSFile turns calendar events that name a country into candidate
+// periods and asks the user to review them.
+func handleICSFile(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	body, err := downloadDocument(msg, bot)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Не удалось загрузить файл."))
+		return
+	}
+	calendar, err := importer.ParseICS(body)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка в календаре: %s.", err)))
+		return
+	}
+	if s.Data.Current == "" || s.Data.Current == "upload_pending" {
+		s.Data.Current = time.Now().Format("02.01.2006")
+	}
+	if len(calendar.Trips) == 0 {
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("📅 В календаре нет событий с названием страны в месте или заголовке (событий: %d).", len(calendar.Skipped))))
+		return
+	}
+
+	s.Temp = nil
+	builder := strings.Builder{}
+	builder.WriteString("📅 Поездки из календаря:\n")
+	for i, trip := range calendar.Trips {
+		s.Temp = append(s.Temp, trip.Period)
+		builder.WriteString(fmt.Sprintf("%d. %s — «%s»\n", i+1, describeCandidate(trip.Period), trip.Summary))
+	}
+	if len(calendar.Skipped) > 0 {
+		builder.WriteString(fmt.Sprintf("\nБез страны, пропущены: %s\n", strings.Join(calendar.Skipped, "; ")))
+	}
+	builder.WriteString("\nЧтобы убрать лишние события, введите их номера через запятую. Затем нажмите «" + tripsApply + "».")
+	s.PendingAction = "awaiting_ics_review"
+	s.SaveSession()
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, builder.String())
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu([]string{tripsApply, previewCancel})
+	bot.Send(reply)
+}
+
+// tripsApply accepts the reviewed trips from a calendar or a ticket.
+const tripsApply = "✅ Добавить поездки"
+
+func handleAwaitingTripsReview(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	text := strings.TrimSpace(msg.Text)
+	switch text {
+	case tripsApply:
+		previewMerge(s.Temp, msg, s, bot)
+		return
+	case previewCancel:
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Поездки не добавлены.")
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+
+	kept, ok := removeNumbered(s.Temp, text)
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Введите номера от 1 до %d через запятую.", len(s.Temp))))
+		return
+	}
+	if len(kept) == 0 {
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Все поездки убраны, ничего не добавлено.")
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+	s.Temp = kept
+	s.SaveSession()
+
+	builder := strings.Builder{}
+	builder.WriteString("🧳 Останутся поездки:\n")
+	for i, p := range s.Temp {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, describeCandidate(p)))
+	}
+	reply := tgbotapi.NewMessage(msg.Chat.ID, builder.String())
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu([]string{tripsApply, previewCancel})
+	bot.Send(reply)
+}
+
+// handleItinerary shows the flights found in a forwarded e-ticket and the
+// stays between them for review.
+func handleItinerary(itinerary importer.Itinerary, msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	if s.Data.Current == "" || s.Data.Current == "upload_pending" {
+		s.Data.Current = time.Now().Format("02.01.2006")
+	}
+	builder := strings.Builder{}
+	builder.WriteString("✈️ Рейсы из билета:\n")
+	for _, f := range itinerary.Flights {
+		builder.WriteString("• " + importer.FormatFlight(f) + "\n")
+	}
+	if len(itinerary.Transits) > 0 {
+		builder.WriteString(fmt.Sprintf("\nПересадки, не считаются пребыванием: %s\n", strings.Join(itinerary.Transits, "; ")))
+	}
+	if len(itinerary.Periods) == 0 {
+		s.SaveSession()
+		builder.WriteString("\nМежду рейсами нет пребывания в другой стране — добавлять нечего.")
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, builder.String()))
+		return
+	}
+
+	s.Temp = itinerary.Periods
+	builder.WriteString("\n🧳 Предлагаемые поездки:\n")
+	for i, p := range s.Temp {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, describeCandidate(p)))
+	}
+	builder.WriteString("\nЧтобы убрать лишние поездки, введите их номера через запятую. Затем нажмите «" + tripsApply + "».")
+	s.PendingAction = "awaiting_ticket_review"
+	s.SaveSession()
+
+	reply := tgbotapi.NewMessage(msg.Chat.ID, builder.String())
+	reply.ReplyMarkup = keyboard.BuildChoiceMenu([]string{tripsApply, previewCancel})
+	bot.Send(reply)
+}
+
+// handleLocationHistory derives periods from a Google Takeout location export
+// and asks the user to review them before replacing or merging.
+func handleLocationHistory(body []byte, msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	history, err := importer.ParseLocationHistory(body)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка в истории местоположений: %s.", err)))
+		return
+	}
+	if s.Data.Current == "" || s.Data.Current == "upload_pending" {
+		s.Data.Current = time.Now().Format("02.01.2006")
+	}
+	if len(history.Periods) == 0 {
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("🗺 По %d точкам не удалось определить ни одной страны.", history.Points)))
+		return
+	}
+
+	s.Temp = history.Periods
+	s.PendingAction = "awaiting_takeout_review"
+	s.SaveSession()
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("🗺 Периоды по истории местоположений (точек: %d", history.Points))
+	if history.Unmatched > 0 {
+		builder.WriteString(fmt.Sprintf(", вне стран: %d", history.Unmatched))
+	}
+	builder.WriteString("):\n")
+	for i, p := range s.Temp {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, describeCandidate(p)))
+	}
+	builder.WriteString("\nСтрана определяется приблизительно, у границы возможны ошибки. Лишние периоды можно убрать, введя их номера через запятую. Затем замените ими текущие периоды или добавьте к ним.")
+	sendLong(msg.Chat.ID, builder.String(), keyboard.BuildChoiceMenu([]string{takeoutReplace, takeoutMerge, previewCancel}), bot)
+}
+
+// Answers to the location history review.
+const (
+	takeoutReplace = "♻️ Заменить текущие периоды"
+	takeoutMerge   = "📥 Добавить к текущим"
+)
+
+func handleAwaitingTakeoutReview(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	text := strings.TrimSpace(msg.Text)
+	switch text {
+	case takeoutReplace:
+		s.BackupSession()
+		s.Data.Periods = s.Temp
+		s.Data.Crossings = nil
+		s.Temp = nil
+		s.PendingAction = ""
+		sendUploadResult(s, msg, bot)
+		return
+	case takeoutMerge:
+		previewMerge(s.Temp, msg, s, bot)
+		return
+	case previewCancel:
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Периоды из истории местоположений не применены.")
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+
+	kept, ok := removeNumbered(s.Temp, text)
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Введите номера от 1 до %d через запятую.", len(s.Temp))))
+		return
+	}
+	if len(kept) == 0 {
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Все периоды убраны, ничего не применено.")
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	}
+	s.Temp = kept
+	s.SaveSession()
+
+	builder := strings.Builder{}
+	builder.WriteString("🗺 Останутся периоды:\n")
+	for i, p := range s.Temp {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, describeCandidate(p)))
+	}
+	sendLong(msg.Chat.ID, builder.String(), keyboard.BuildChoiceMenu([]string{takeoutReplace, takeoutMerge, previewCancel}), bot)
+}
+
+// maxMessageLength keeps messages under Telegram's limit of 4096 characters.
+const maxMessageLength = 4000
+
+// sendLong splits a long text by lines into several messages; the keyboard
+// goes with the last one.
+func sendLong(chatID int64, text string, markup interface{}, bot *tgbotapi.BotAPI) {
+	var chunks []string
+	current := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if current != "" && len([]rune(current))+len([]rune(line)) > maxMessageLength {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		current += line
+	}
+	chunks = append(chunks, current)
+	for i, chunk := range chunks {
+		reply := tgbotapi.NewMessage(chatID, chunk)
+		if i == len(chunks)-1 {
+			reply.ReplyMarkup = markup
+		}
+		bot.Send(reply)
+	}
+}
+
+// isXLSX recognises Excel files; the bot reads the border crossing
+// statement from them.
+func isXLSX(doc *tgbotapi.Document) bool {
+	return strings.HasSuffix(strings.ToLower(doc.FileName), ".xlsx") ||
+		doc.MimeType == "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+func isPDF(doc *tgbotapi.Document) bool {
+	return strings.HasSuffix(strings.ToLower(doc.FileName), ".pdf") || doc.MimeType == "application/pdf"
+}
+
+// handleStatementFile reads the Gosuslugi border crossing statement and
+// shows the crossings and derived periods for review.
+func handleStatementFile(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	body, err := downloadDocument(msg, bot)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Не удалось загрузить файл."))
+		return
+	}
+	rows, err := importer.ReadXLSX(body)
+	if err == nil {
+		s.TempCrossings, err = importer.ParseBorderStatement(rows)
+	}
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Ошибка в выписке: %s.", err)))
+		return
+	}
+	if s.Data.Current == "" || s.Data.Current == "upload_pending" {
+		s.Data.Current = time.Now().Format("02.01.2006")
+	}
+	validator.SortCrossings(s.TempCrossings)
+	s.PendingAction = "awaiting_statement_review"
+	s.SaveSession()
+
+	periods, issues := validator.DeriveCrossings(s.TempCrossings)
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("🛂 Выписка о пересечениях границы: пересечений %d, периодов %d.\n", len(s.TempCrossings), len(periods)))
+	for i, c := range s.TempCrossings {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, importer.FormatCrossing(c)))
+	}
+	builder.WriteString("\nПериоды:\n")
+	for i, p := range periods {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, describeCandidate(p)))
+	}
+	if text := validator.Render(issues); text != "" {
+		builder.WriteString("\n" + text)
+	}
+	options := []string{statementReplace}
+	switch {
+	case len(s.Data.Crossings) > 0:
+		options = append(options, statementAppend)
+	case !s.IsEmpty():
+		builder.WriteString("\n⚠️ Журнала пересечений пока нет, периоды введены вручную. «Заменить» заменит их все периодами из выписки (прежние сохранятся в резервной копии), «Добавить к периодам» добавит новые периоды к текущим, показав повторы и пересечения.\n")
+		options = append(options, statementMerge)
+	}
+	options = append(options, previewCancel)
+	sendLong(msg.Chat.ID, builder.String(), keyboard.BuildChoiceMenu(options), bot)
+}
+
+// Answers to the statement review.
+const (
+	statementReplace = "✅ Заменить журнал пересечений"
+	statementAppend  = "📥 Добавить к журналу"
+	statementMerge   = "📥 Добавить к периодам"
+)
+
+func handleAwaitingStatementReview(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	switch strings.TrimSpace(msg.Text) {
+	case statementReplace:
+		s.BackupSession()
+		s.Data.Crossings = s.TempCrossings
+	case statementAppend:
+		s.BackupSession()
+		for _, c := range s.TempCrossings {
+			if !slices.Contains(s.Data.Crossings, c) {
+				s.Data.Crossings = append(s.Data.Crossings, c)
+			}
+		}
+	case statementMerge:
+		// без журнала периоды выписки сверяются с введёнными вручную
+		periods, _ := validator.DeriveCrossings(s.TempCrossings)
+		s.TempCrossings = nil
+		previewMerge(periods, msg, s, bot)
+		return
+	case previewCancel:
+		s.TempCrossings = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		reply := tgbotapi.NewMessage(msg.Chat.ID, "❌ Выписка не применена.")
+		reply.ReplyMarkup = keyboard.BuildMainMenu(s)
+		bot.Send(reply)
+		return
+	default:
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите ответ кнопкой."))
+		return
+	}
+	s.TempCrossings = nil
+	s.PendingAction = ""
+	syncCrossings(s)
+	sendUploadResult(s, msg, bot)
+}
+
+// removeNumbered drops the items whose 1-based numbers are listed in text,
+// separated by commas or spaces.
+func removeNumbered[T any](items []T, text string) ([]T, bool) {
+	remove := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(items) {
+			return nil, false
+		}
+		remove[n-1] = true
+	}
+	var kept []T
+	for i, item := range items {
+		if !remove[i] {
+			kept = append(kept, item)
+		}
+	}
+	return kept, true
+}
+
+// detachCrossings drops the crossing log before a direct period edit: the
+// next change of the log would rebuild the periods and lose the edit. The
+// previous data are kept in the backup.
+func detachCrossings(s *model.Session, msg *tgbotapi.Message, bot *tgbotapi.BotAPI) {
+	if len(s.Data.Crossings) == 0 {
+		return
+	}
+	s.BackupSession()
+	s.Data.Crossings = nil
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "ℹ️ Периоды были построены по журналу пересечений (/crossings). Журнал удалён, чтобы его следующее изменение не затёрло правку; прежние данные сохранены в резервной копии."))
+}
+
+// syncCrossings sorts the crossing log and rebuilds the periods from it.
+func syncCrossings(s *model.Session) {
+	validator.SortCrossings(s.Data.Crossings)
+	s.Data.Periods, _ = validator.DeriveCrossings(s.Data.Crossings)
+}
+
+func handleAwaitingCrossingsAdd(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	crossings, err := importer.ParseCrossings(msg.Text)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ %s.", err)))
+		return
+	}
+	replaced := len(s.Data.Crossings) == 0 && !s.IsEmpty()
+	if replaced {
+		s.BackupSession()
+	}
+	s.Data.Crossings = append(s.Data.Crossings, crossings...)
+	syncCrossings(s)
+	if s.Data.Current == "" {
+		s.Data.Current = time.Now().Format("02.01.2006")
+	}
+	s.SaveSession()
+
+	text := fmt.Sprintf("✅ Добавлено пересечений: %d.", len(crossings))
+	if replaced {
+		text += " Теперь периоды строятся по пересечениям, прежние периоды сохранены в резервной копии."
+	}
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, text))
+	handleCrossingsCommand(s, msg, bot)
+}
+
+func handleAwaitingCrossingEditIndex(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	index, err := strconv.Atoi(strings.TrimSpace(msg.Text))
+	if err != nil || index < 1 || index > len(s.Data.Crossings) {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Введите корректный номер пересечения."))
+		return
+	}
+	s.EditingIndex = index - 1
+	s.PendingAction = "awaiting_crossing_edit"
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("Сейчас: %s\nВведите пересечение заново в том же формате.", importer.FormatCrossing(s.Data.Crossings[s.EditingIndex]))))
+}
+
+func handleAwaitingCrossingEdit(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	crossings, err := importer.ParseCrossings(msg.Text)
+	if err != nil || len(crossings) != 1 {
+		if err == nil {
+			err = fmt.Errorf("введите одно пересечение")
+		}
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ %s.", err)))
+		return
+	}
+	if s.EditingIndex < 0 || s.EditingIndex >= len(s.Data.Crossings) {
+		handleCrossingsCommand(s, msg, bot)
+		return
+	}
+	s.Data.Crossings[s.EditingIndex] = crossings[0]
+	syncCrossings(s)
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "✅ Пересечение изменено."))
+	handleCrossingsCommand(s, msg, bot)
+}
+
+func handleAwaitingCrossingsDelete(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	kept, ok := removeNumbered(s.Data.Crossings, strings.TrimSpace(msg.Text))
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Введите номера от 1 до %d через запятую.", len(s.Data.Crossings))))
+		return
+	}
+	s.Data.Crossings = kept
+	syncCrossings(s)
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "🗑 Пересечения удалены."))
+	handleCrossingsCommand(s, msg, bot)
+}
+
+// describeCandidate is a one-line view of an imported period.
+func describeCandidate(p model.Period) string {
+	flag := utils.CountryToFlag(utils.CountryCodeMap[p.Country])
+	in, out := strings.TrimSpace(p.In+" "+p.InTime), strings.TrimSpace(p.Out+" "+p.OutTime)
+	if in == "" {
+		in = "…"
+	}
+	if out == "" {
+		out = "…"
+	}
+	return fmt.Sprintf("%s %s: %s — %s", flag, p.Country, in, out)
+}
+
+// Answers to the merge preview.
+const (
+	mergeApply    = "✅ Добавить новые"
+	mergeApplyAll = "📥 Добавить вместе с конфликтующими"
+)
+
+func handleAwaitingMergeConfirm(msg *tgbotapi.Message, s *model.Session, bot *tgbotapi.BotAPI) {
+	text := strings.TrimSpace(msg.Text)
+	if text == previewCancel {
+		s.Temp = nil
+		s.PendingAction = ""
+		s.SaveSession()
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "❌ Изменения не применены."))
+		handlePeriodsCommand(s, msg, bot)
+		return
+	}
+	if text != mergeApply && text != mergeApplyAll {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, "⛔ Выберите вариант из списка."))
+		return
+	}
+	result, err := validator.Merge(s.Data.Periods, s.Temp)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(msg.Chat.ID, fmt.Sprintf("⛔ Не удалось сравнить периоды: %s.", err)))
+		return
+	}
+	s.BackupSession()
+	s.Data.Periods = result.Periods(text == mergeApplyAll)
+	reply := "✅ Периоды добавлены."
+	if len(s.Data.Crossings) > 0 {
+		// журнал перестроил бы периоды и стёр добавленные
+		s.Data.Crossings = nil
+		reply += " Журнал пересечений удалён: периоды теперь редактируются напрямую."
+	}
+	s.Temp = nil
+	s.PendingAction = ""
+	s.SaveSession()
+	bot.Send(tgbotapi.NewMessage(msg.Chat.ID, reply))
+	handlePeriodsCommand(s, msg, bot)
+}
@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// bordersGz holds the simplified country polygons; the format is described
+// in the header of the file.
+//
+//go:embed borders.tsv.gz
+var bordersGz []byte
+
+// bandHeight is the height, in degrees, of the latitude bands that index the
+// border edges.
+const bandHeight = 0.1
+
+// edge is a segment of a border ring, in degrees of longitude (x) and
+// latitude (y).
+type edge struct {
+	country        int
+	x1, y1, x2, y2 float64
+}
+
+// borderIndex keeps the border edges by latitude band: a ray cast east from
+// a point crosses only the edges of the point's own band.
+type borderIndex struct {
+	codes []string
+	bands [][]edge
+}
+
+var (
+	bordersOnce sync.Once
+	borders     borderIndex
+)
+
+func loadBorders() *borderIndex {
+	bordersOnce.Do(func() {
+		borders.bands = make([][]edge, int(180/bandHeight)+1)
+		r, err := gzip.NewReader(bytes.NewReader(bordersGz))
+		if err != nil {
+			return
+		}
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return
+		}
+		ids := make(map[string]int)
+		for _, line := range strings.Split(string(body), "\n") {
+			code, ring, ok := strings.Cut(line, "\t")
+			if !ok || strings.HasPrefix(line, "#") {
+				continue
+			}
+			points := parseRing(ring)
+			if len(points) < 3 {
+				continue
+			}
+			id, ok := ids[code]
+			if !ok {
+				id = len(borders.codes)
+				ids[code] = id
+				borders.codes = append(borders.codes, code)
+			}
+			for i, a := range points {
+				b := points[(i+1)%len(points)]
+				borders.add(edge{country: id, x1: a[0], y1: a[1], x2: b[0], y2: b[1]})
+			}
+		}
+	})
+	return &borders
+}
+
+// parseRing decodes "x,y dx,dy ..." in 1e-4 degrees into absolute points.
+func parseRing(ring string) [][2]float64 {
+	var points [][2]float64
+	var x, y int
+	for i, pair := range strings.Fields(ring) {
+		xText, yText, ok := strings.Cut(pair, ",")
+		dx, errX := strconv.Atoi(xText)
+		dy, errY := strconv.Atoi(yText)
+		if !ok || errX != nil || errY != nil {
+			return nil
+		}
+		if i == 0 {
+			x, y = dx, dy
+		} else {
+			x, y = x+dx, y+dy
+		}
+		points = append(points, [2]float64{float64(x) / 1e4, float64(y) / 1e4})
+	}
+	return points
+}
+
+func (b *borderIndex) add(e edge) {
+	if e.y1 == e.y2 {
+		// горизонтальное ребро луч на восток не пересекает
+		return
+	}
+	for i := b.band(min(e.y1, e.y2)); i <= b.band(max(e.y1, e.y2)); i++ {
+		b.bands[i] = append(b.bands[i], e)
+	}
+}
+
+func (b *borderIndex) band(lat float64) int {
+	i := int((lat + 90) / bandHeight)
+	return max(0, min(len(b.bands)-1, i))
+}
+
+// countries returns the ISO codes of the polygons containing the point,
+// normally one; borders simplified on both sides may overlap by a few metres.
+func (b *borderIndex) countries(lat, lon float64) []string {
+	inside := make(map[int]bool)
+	for _, e := range b.bands[b.band(lat)] {
+		if (e.y1 > lat) != (e.y2 > lat) && lon < e.x1+(lat-e.y1)*(e.x2-e.x1)/(e.y2-e.y1) {
+			inside[e.country] = !inside[e.country]
+		}
+	}
+	var codes []string
+	for id, in := range inside {
+		if in {
+			codes = append(codes, b.codes[id])
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,27 @@
+package importer
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestBordersData(t *testing.T) {
+	b := loadBorders()
+	if len(b.codes) < 200 {
+		t.Fatalf("only %d countries loaded", len(b.codes))
+	}
+	// у каждой страны из границ, городов и аэропортов есть название
+	codes := append([]string(nil), b.codes...)
+	for _, p := range loadPlaces() {
+		codes = append(codes, p.code)
+	}
+	for _, a := range loadAirports() {
+		codes = append(codes, a.code)
+	}
+	for _, code := range codes {
+		if _, ok := utils.CountryByCode(code); !ok {
+			t.Errorf("no country name for %s", code)
+		}
+	}
+}
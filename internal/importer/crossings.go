@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// crossingDirections maps the words of a crossing line to directions.
+var crossingDirections = map[string]string{
+	"въезд": model.DirectionEntry, "въехал": model.DirectionEntry, "въехала": model.DirectionEntry,
+	"прибытие": model.DirectionEntry, "entry": model.DirectionEntry, "in": model.DirectionEntry,
+	"выезд": model.DirectionExit, "выехал": model.DirectionExit, "выехала": model.DirectionExit,
+	"убытие": model.DirectionExit, "exit": model.DirectionExit, "out": model.DirectionExit,
+}
+
+// ParseCrossings reads crossings typed one per line as
+// "ДД.ММ.ГГГГ [ЧЧ:ММ] въезд|выезд Страна[, пункт пропуска]". A preposition
+// before the country ("выезд из России") is allowed.
+func ParseCrossings(text string) ([]model.Crossing, error) {
+	var crossings []model.Crossing
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		c, err := parseCrossing(line)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", i+1, err)
+		}
+		crossings = append(crossings, c)
+	}
+	if len(crossings) == 0 {
+		return nil, fmt.Errorf("нет ни одного пересечения")
+	}
+	return crossings, nil
+}
+
+func parseCrossing(line string) (model.Crossing, error) {
+	var c model.Crossing
+	head, checkpoint, _ := strings.Cut(line, ",")
+	c.Checkpoint = strings.TrimSpace(checkpoint)
+	fields := strings.Fields(head)
+	if len(fields) < 3 {
+		return c, fmt.Errorf("ожидается «дата направление страна», получено «%s»", line)
+	}
+
+	date, err := utils.ParseAnyDate(fields[0])
+	if err != nil {
+		return c, fmt.Errorf("некорректная дата «%s»", fields[0])
+	}
+	c.Date = utils.FormatDate(date)
+	fields = fields[1:]
+	if _, err := time.Parse("15:04", fields[0]); err == nil {
+		c.Time = fields[0]
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 {
+		return c, fmt.Errorf("не указано направление")
+	}
+	direction, ok := crossingDirections[strings.ToLower(fields[0])]
+	if !ok {
+		return c, fmt.Errorf("направление «%s» — ожидается въезд или выезд", fields[0])
+	}
+	c.Direction = direction
+	fields = fields[1:]
+	if len(fields) > 0 {
+		switch strings.ToLower(fields[0]) {
+		case "в", "во", "из", "to", "from":
+			fields = fields[1:]
+		}
+	}
+
+	c.Country = crossingCountry(strings.Join(fields, " "))
+	if c.Country == "" {
+		return c, fmt.Errorf("не указана страна")
+	}
+	return c, nil
+}
+
+// crossingCountry accepts a name, an ISO code or a name in any case form.
+func crossingCountry(value string) string {
+	country := utils.NormalizeCountry(value)
+	if _, ok := utils.CountryCodeMap[country]; ok {
+		return country
+	}
+	if found, ok := utils.FindCountry(value); ok {
+		return found
+	}
+	return country
+}
+
+// FormatCrossing is the one-line form ParseCrossings reads back.
+func FormatCrossing(c model.Crossing) string {
+	parts := []string{c.Date}
+	if c.Time != "" {
+		parts = append(parts, c.Time)
+	}
+	parts = append(parts, model.DirectionTitle(c.Direction), c.Country)
+	line := strings.Join(parts, " ")
+	if c.Checkpoint != "" {
+		line += ", " + c.Checkpoint
+	}
+	return line
+}
@@ -0,0 +1,37 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestParseCrossings(t *testing.T) {
+	got, err := ParseCrossings(`01.02.2024 выезд из России, Шереметьево
+01.02.2024 15:40 въезд в Грузию
+
+2024-03-15 exit GE, Upper Lars`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Crossing{
+		{Date: "01.02.2024", Direction: model.DirectionExit, Country: "Россия", Checkpoint: "Шереметьево"},
+		{Date: "01.02.2024", Time: "15:40", Direction: model.DirectionEntry, Country: "Грузия"},
+		{Date: "15.03.2024", Direction: model.DirectionExit, Country: "Грузия", Checkpoint: "Upper Lars"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if line := FormatCrossing(want[1]); line != "01.02.2024 15:40 въезд Грузия" {
+		t.Errorf("format: %q", line)
+	}
+}
+
+func TestParseCrossingsErrors(t *testing.T) {
+	for _, text := range []string{"", "01.02.2024 въезд", "01.02.2024 транзит Грузия", "вчера въезд Грузия"} {
+		if _, err := ParseCrossings(text); err == nil {
+			t.Errorf("%q: expected error", text)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+// utf8BOM is written by Excel in front of UTF-8 CSV files.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// csvColumns maps header names to period fields.
+var csvColumns = map[string]string{
+	"in": "in", "въезд": "in", "дата въезда": "in", "arrival": "in", "from": "in", "start": "in", "с": "in",
+	"out": "out", "выезд": "out", "дата выезда": "out", "departure": "out", "to": "out", "end": "out", "по": "out",
+	"country": "country", "страна": "country",
+	"region": "region", "регион": "region", "штат": "region",
+	"purpose": "purpose", "цель": "purpose",
+	"in_time": "in_time", "время въезда": "in_time",
+	"out_time": "out_time", "время выезда": "out_time",
+	"tz": "tz", "часовой пояс": "tz",
+	"flags": "flags",
+}
+
+// csvExportColumns is the column order of FormatCSV.
+var csvExportColumns = []string{"in", "out", "country", "region", "purpose", "in_time", "out_time", "tz", "flags"}
+
+// ParseCSV reads periods from a spreadsheet export. The separator is ";" or
+// ",", whichever the first line has more of. A header row is recognised by
+// its names; without one the columns are in, out, country. Dates may use
+// any format ParseAnyDate accepts, countries may be ISO codes.
+func ParseCSV(body []byte) ([]model.Period, error) {
+	body = bytes.TrimPrefix(body, utf8BOM)
+	firstLine, _, _ := bytes.Cut(body, []byte("\n"))
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.Comma = ','
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("файл пуст")
+	}
+
+	columns := map[string]int{"in": 0, "out": 1, "country": 2}
+	start := 0
+	if header, ok := csvHeader(rows[0]); ok {
+		columns = header
+		start = 1
+	}
+	for _, required := range []string{"in", "out", "country"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("нет столбца %s", required)
+		}
+	}
+
+	var periods []model.Period
+	for i, row := range rows[start:] {
+		line := start + i + 1
+		cell := func(name string) string {
+			if idx, ok := columns[name]; ok && idx < len(row) {
+				return strings.TrimSpace(row[idx])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		p := model.Period{
+			Country:  utils.NormalizeCountry(cell("country")),
+			Region:   cell("region"),
+			Purpose:  cell("purpose"),
+			InTime:   cell("in_time"),
+			OutTime:  cell("out_time"),
+			TimeZone: cell("tz"),
+		}
+		if flags := strings.Fields(cell("flags")); len(flags) > 0 {
+			p.Flags = flags
+		}
+		if p.Country == "" {
+			return nil, fmt.Errorf("строка %d: не указана страна", line)
+		}
+		for _, field := range []struct {
+			name  string
+			title string
+			dest  *string
+		}{{"in", "въезда", &p.In}, {"out", "выезда", &p.Out}} {
+			value := cell(field.name)
+			if value == "" {
+				continue
+			}
+			date, err := utils.ParseAnyDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: некорректная дата %s «%s»", line, field.title, value)
+			}
+			*field.dest = utils.FormatDate(date)
+		}
+		periods = append(periods, p)
+	}
+	if len(periods) == 0 {
+		return nil, fmt.Errorf("в файле нет периодов")
+	}
+	return periods, nil
+}
+
+// csvHeader returns the column indexes when the row is a header.
+func csvHeader(row []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, name := range row {
+		if field, ok := csvColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	return columns, len(columns) > 0
+}
+
+// FormatCSV writes the periods with a header row, ";"-separated and with a
+// BOM so that spreadsheets open Cyrillic text correctly.
+func FormatCSV(periods []model.Period) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(utf8BOM)
+	w := csv.NewWriter(&buf)
+	w.Comma = ';'
+	_ = w.Write(csvExportColumns)
+	for _, p := range periods {
+		_ = w.Write([]string{p.In, p.Out, p.Country, p.Region, p.Purpose, p.InTime, p.OutTime, p.TimeZone, strings.Join(p.Flags, " ")})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
@@ -0,0 +1,65 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestParseCSV(t *testing.T) {
+	want := []model.Period{
+		{In: "01.01.2024", Out: "31.03.2024", Country: "Россия"},
+		{In: "31.03.2024", Country: "Грузия"},
+	}
+	tests := map[string]string{
+		"header with semicolons": "\xef\xbb\xbfСтрана;Дата въезда;Дата выезда\nRU;01.01.2024;31.03.2024\nГрузия;2024-03-31;\n",
+		"no header":              "01.01.2024,31.03.2024,Россия\n\n31/03/2024,,GE\n",
+		"english header":         "From, To, Country\n1.1.2024, 2024-03-31, россия\n31.03.24, , Грузия\n",
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseCSV([]byte(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %+v", got)
+			}
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := map[string]string{
+		"bad date":       "in;out;country\n01.13.2024;;Россия\n",
+		"no country":     "in;out;country\n01.01.2024;;\n",
+		"missing column": "in;country\n01.01.2024;Россия\n",
+		"empty":          "",
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseCSV([]byte(body)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestFormatCSVRoundTrip(t *testing.T) {
+	periods := []model.Period{
+		{In: "01.01.2024", Out: "31.03.2024", Country: "США", Region: "NY", OutTime: "23:30", TimeZone: "America/New_York"},
+		{In: "31.03.2024", Country: "Израиль", Purpose: model.PurposeTreatment, Flags: []string{model.FlagExemptIndividual}},
+	}
+	body, err := FormatCSV(periods)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseCSV(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, periods) {
+		t.Fatalf("round trip changed periods: %+v", got)
+	}
+}
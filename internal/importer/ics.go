@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// CalendarEvent is a VEVENT turned into a candidate period.
+type CalendarEvent struct {
+	Summary string
+	Period  model.Period
+}
+
+// CalendarImport is the result of reading an iCalendar file.
+type CalendarImport struct {
+	// Trips are events whose location or title names a country, by date.
+	Trips []CalendarEvent
+	// Skipped are the titles of events without a recognised country.
+	Skipped []string
+}
+
+// icsProperty is one content line: NAME;PARAM=VALUE:value.
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICS reads VEVENTs from an iCalendar file. The country is looked up in
+// LOCATION first, then in SUMMARY. All-day events end the day before DTEND;
+// timed events keep their local times and TZID. Recurrence rules are ignored.
+func ParseICS(body []byte) (CalendarImport, error) {
+	var result CalendarImport
+	lines := unfoldICS(body)
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return result, fmt.Errorf("это не файл календаря iCalendar")
+	}
+
+	var event map[string]icsProperty
+	for _, line := range lines {
+		prop, ok := parseICSLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VEVENT"):
+			event = make(map[string]icsProperty)
+		case prop.name == "END" && strings.EqualFold(prop.value, "VEVENT"):
+			if event == nil {
+				continue
+			}
+			trip, err := icsTrip(event)
+			if err != nil {
+				return result, err
+			}
+			if trip.Period.Country == "" {
+				result.Skipped = append(result.Skipped, trip.Summary)
+			} else {
+				result.Trips = append(result.Trips, trip)
+			}
+			event = nil
+		case event != nil:
+			if _, seen := event[prop.name]; !seen {
+				event[prop.name] = prop
+			}
+		}
+	}
+	sort.SliceStable(result.Trips, func(i, j int) bool {
+		a, _ := utils.ParseDate(result.Trips[i].Period.In)
+		b, _ := utils.ParseDate(result.Trips[j].Period.In)
+		return a.Before(b)
+	})
+	return result, nil
+}
+
+func icsTrip(event map[string]icsProperty) (CalendarEvent, error) {
+	trip := CalendarEvent{Summary: icsUnescape(event["SUMMARY"].value)}
+	location := icsUnescape(event["LOCATION"].value)
+	country, ok := utils.FindCountry(location)
+	if !ok {
+		country, ok = utils.FindCountry(trip.Summary)
+	}
+	if !ok {
+		return trip, nil
+	}
+
+	start, ok := event["DTSTART"]
+	if !ok {
+		return trip, fmt.Errorf("у события «%s» нет даты начала", trip.Summary)
+	}
+	in, inTime, allDay, err := icsDate(start)
+	if err != nil {
+		return trip, fmt.Errorf("событие «%s»: %w", trip.Summary, err)
+	}
+	out, outTime := in, inTime
+	if end, ok := event["DTEND"]; ok {
+		if out, outTime, _, err = icsDate(end); err != nil {
+			return trip, fmt.Errorf("событие «%s»: %w", trip.Summary, err)
+		}
+		// DTEND целодневного события не входит в событие
+		if allDay && out.After(in) {
+			out = out.AddDate(0, 0, -1)
+		}
+	}
+
+	trip.Period = model.Period{In: utils.FormatDate(in), Out: utils.FormatDate(out), Country: country}
+	if !allDay {
+		trip.Period.InTime, trip.Period.OutTime = inTime, outTime
+		trip.Period.TimeZone = start.params["TZID"]
+		if strings.HasSuffix(start.value, "Z") {
+			trip.Period.TimeZone = "UTC"
+		}
+	}
+	return trip, nil
+}
+
+// icsDate parses DATE and DATE-TIME values; the date and time are returned
+// as written, in the event's own zone.
+func icsDate(prop icsProperty) (time.Time, string, bool, error) {
+	value := strings.TrimSuffix(prop.value, "Z")
+	if prop.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		d, err := time.Parse("20060102", value)
+		if err != nil {
+			return d, "", true, fmt.Errorf("некорректная дата «%s»", prop.value)
+		}
+		return d, "", true, nil
+	}
+	t, err := time.Parse("20060102T150405", value)
+	if err != nil {
+		return t, "", false, fmt.Errorf("некорректная дата «%s»", prop.value)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), t.Format("15:04"), false, nil
+}
+
+// unfoldICS joins continuation lines that start with a space or a tab.
+func unfoldICS(body []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(body, utf8BOM)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func parseICSLine(line string) (icsProperty, bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return icsProperty{}, false
+	}
+	parts := strings.Split(head, ";")
+	prop := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return prop, true
+}
+
+func icsUnescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,64 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+const calendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+SUMMARY:Командировка
+LOCATION:Алматы\, Казахстан
+DTSTART;TZID=Asia/Almaty:20240510T083000
+DTEND;TZID=Asia/Almaty:20240514T200000
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Отпуск в Грузии
+DTSTART;VALUE=DATE:20240301
+DTEND;VALUE=DATE:20240311
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Стоматолог
+DTSTART:20240320T090000Z
+DTEND:20240320T100000Z
+END:VEVENT
+BEGIN:VEVENT
+SUMMARY:Flight to Tbilisi
+LOCATION:Tbilisi International Airport\, Tbilisi\, Geo
+ rgia
+DTSTART:20240601T050000Z
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParseICS(t *testing.T) {
+	got, err := ParseICS([]byte(strings.ReplaceAll(calendar, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Period{
+		{In: "01.03.2024", Out: "10.03.2024", Country: "Грузия"},
+		{In: "10.05.2024", Out: "14.05.2024", Country: "Казахстан", InTime: "08:30", OutTime: "20:00", TimeZone: "Asia/Almaty"},
+		{In: "01.06.2024", Out: "01.06.2024", Country: "Грузия", InTime: "05:00", OutTime: "05:00", TimeZone: "UTC"},
+	}
+	var periods []model.Period
+	for _, trip := range got.Trips {
+		periods = append(periods, trip.Period)
+	}
+	if !reflect.DeepEqual(periods, want) {
+		t.Fatalf("got %+v", periods)
+	}
+	if !reflect.DeepEqual(got.Skipped, []string{"Стоматолог"}) {
+		t.Fatalf("unexpected skipped events %v", got.Skipped)
+	}
+}
+
+func TestParseICSNotCalendar(t *testing.T) {
+	if _, err := ParseICS([]byte(`{"periods": []}`)); err == nil {
+		t.Fatal("expected an error for a non-calendar file")
+	}
+}
@@ -0,0 +1,189 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// statementCountry is the country whose border the FSB statement records.
+const statementCountry = "Россия"
+
+// statementHeaders are keywords of the statement columns; a header cell is
+// assigned to the first field whose keyword it contains.
+var statementHeaders = []struct {
+	field    string
+	keywords []string
+}{
+	{"date", []string{"дата"}},
+	{"direction", []string{"направлен", "въезд", "выезд", "операци"}},
+	{"time", []string{"время"}},
+	{"checkpoint", []string{"пункт", "кпп"}},
+	{"country", []string{"стран", "государств"}},
+}
+
+// clockPattern finds a time of day inside a date cell.
+var clockPattern = regexp.MustCompile(`\b(\d{1,2}):(\d{2})`)
+
+// excelEpoch is day zero of Excel serial dates.
+var excelEpoch = time.Date(1899, time.December, 30, 0, 0, 0, 0, time.UTC)
+
+// ParseBorderStatement reads the table of the border crossing statement
+// ordered on Gosuslugi (FSB data). The header row is found by the date and
+// direction columns; titles above it, empty rows, repeated headers and notes
+// after the last crossing are skipped, while a table row with a missing or
+// unreadable date is an error, so no crossing is lost silently. Each row is
+// a crossing of the Russian border; when the table names the country of
+// destination or departure, the matching entry or exit of that country is
+// added on the same day.
+func ParseBorderStatement(rows [][]string) ([]model.Crossing, error) {
+	columns, start := statementHeader(rows)
+	if columns == nil {
+		return nil, fmt.Errorf("не найдена таблица с датой и направлением пересечения")
+	}
+	cell := func(row []string, field string) string {
+		if idx, ok := columns[field]; ok && idx < len(row) {
+			return strings.TrimSpace(row[idx])
+		}
+		return ""
+	}
+
+	var crossings []model.Crossing
+	// строка без понятной даты — ошибка, только если за ней таблица
+	// продолжается: после последнего пересечения идут подписи и примечания
+	var broken error
+	for i, row := range rows[start:] {
+		line := start + i + 1
+		if _, header := headerColumns(row); header {
+			continue
+		}
+		rawDate := cell(row, "date")
+		direction, ok := statementDirection(cell(row, "direction"))
+		if rawDate == "" {
+			if ok && broken == nil {
+				broken = fmt.Errorf("строка %d: не указана дата пересечения", line)
+			}
+			continue
+		}
+		date, clock, err := statementDate(rawDate)
+		if err != nil {
+			if broken == nil {
+				broken = fmt.Errorf("строка %d: %w", line, err)
+			}
+			continue
+		}
+		if broken != nil {
+			return nil, broken
+		}
+		if t := cell(row, "time"); t != "" {
+			if c, ok := findClock(t); ok {
+				clock = c
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("строка %d: непонятное направление «%s»", line, cell(row, "direction"))
+		}
+
+		home := model.Crossing{Date: date, Time: clock, Direction: direction, Country: statementCountry, Checkpoint: cell(row, "checkpoint")}
+		other := model.Crossing{Date: date, Time: clock, Country: crossingCountry(cell(row, "country"))}
+		if other.Country == "" || other.Country == statementCountry {
+			crossings = append(crossings, home)
+			continue
+		}
+		if direction == model.DirectionExit {
+			other.Direction = model.DirectionEntry
+			crossings = append(crossings, home, other)
+		} else {
+			other.Direction = model.DirectionExit
+			crossings = append(crossings, other, home)
+		}
+	}
+	if len(crossings) == 0 {
+		return nil, fmt.Errorf("в таблице нет пересечений")
+	}
+	return crossings, nil
+}
+
+// statementHeader finds the header row and returns the column indexes and
+// the index of the first data row.
+func statementHeader(rows [][]string) (map[string]int, int) {
+	for i, row := range rows {
+		if columns, ok := headerColumns(row); ok {
+			return columns, i + 1
+		}
+	}
+	return nil, 0
+}
+
+// headerColumns matches the cells of a row against statementHeaders; the row
+// is a header when it has the date and direction columns.
+func headerColumns(row []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for j, value := range row {
+		value = strings.ToLower(value)
+		for _, h := range statementHeaders {
+			if _, seen := columns[h.field]; seen {
+				continue
+			}
+			matched := false
+			for _, keyword := range h.keywords {
+				if strings.Contains(value, keyword) {
+					matched = true
+					break
+				}
+			}
+			if matched {
+				columns[h.field] = j
+				break
+			}
+		}
+	}
+	_, hasDate := columns["date"]
+	_, hasDirection := columns["direction"]
+	return columns, hasDate && hasDirection
+}
+
+// statementDate reads a date cell with an optional time: text in any format
+// ParseAnyDate accepts or an Excel serial number.
+func statementDate(value string) (string, string, error) {
+	clock, _ := findClock(value)
+	if date, err := utils.ParseAnyDate(value); err == nil {
+		return utils.FormatDate(date), clock, nil
+	}
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 1 || serial > 100000 {
+		return "", "", fmt.Errorf("некорректная дата «%s»", value)
+	}
+	days := math.Floor(serial)
+	at := excelEpoch.AddDate(0, 0, int(days)).Add(time.Duration(math.Round((serial-days)*24*60)) * time.Minute)
+	if serial != days {
+		clock = at.Format("15:04")
+	}
+	return utils.FormatDate(at), clock, nil
+}
+
+// findClock returns the first "15:04" time in the text.
+func findClock(text string) (string, bool) {
+	m := clockPattern.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	return fmt.Sprintf("%02d:%s", hour, m[2]), true
+}
+
+func statementDirection(value string) (string, bool) {
+	value = strings.ToLower(value)
+	switch {
+	case strings.Contains(value, "выезд"), strings.Contains(value, "убыт"):
+		return model.DirectionExit, true
+	case strings.Contains(value, "въезд"), strings.Contains(value, "прибыт"):
+		return model.DirectionEntry, true
+	}
+	return "", false
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestParseBorderStatement(t *testing.T) {
+	rows := [][]string{
+		{"Сведения о датах въезда и выезда"},
+		{"Иванов Иван Иванович"},
+		{"№ п/п", "Дата и время пересечения", "Направление", "Наименование пункта пропуска", "Страна следования / отправления"},
+		{"1", "01.02.2024 10:15:00", "Выезд из РФ", "Шереметьево (аэропорт)", "ТУРЦИЯ"},
+		{"2", "45341.875", "Въезд в РФ", "Внуково", "Турецкая Республика"},
+		{"№ п/п", "Дата и время пересечения", "Направление", "Наименование пункта пропуска", "Страна следования / отправления"},
+		{"3", "05.04.2024", "Выезд", "Верхний Ларс", ""},
+		{"", "", "", "", ""},
+		{"Дата формирования выписки: 20.06.2024"},
+		{"", "Подпись уполномоченного лица"},
+	}
+	got, err := ParseBorderStatement(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Crossing{
+		{Date: "01.02.2024", Time: "10:15", Direction: model.DirectionExit, Country: "Россия", Checkpoint: "Шереметьево (аэропорт)"},
+		{Date: "01.02.2024", Time: "10:15", Direction: model.DirectionEntry, Country: "Турция"},
+		{Date: "19.02.2024", Time: "21:00", Direction: model.DirectionExit, Country: "Турция"},
+		{Date: "19.02.2024", Time: "21:00", Direction: model.DirectionEntry, Country: "Россия", Checkpoint: "Внуково"},
+		{Date: "05.04.2024", Direction: model.DirectionExit, Country: "Россия", Checkpoint: "Верхний Ларс"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseBorderStatementErrors(t *testing.T) {
+	if _, err := ParseBorderStatement([][]string{{"in", "out", "country"}}); err == nil {
+		t.Error("expected error without date and direction columns")
+	}
+	rows := [][]string{{"Дата", "Направление"}, {"01.02.2024", "транзит"}}
+	if _, err := ParseBorderStatement(rows); err == nil {
+		t.Error("expected error for unknown direction")
+	}
+	// непонятная дата внутри таблицы не пропускается молча
+	rows = [][]string{
+		{"Дата", "Направление"},
+		{"01.02.2024", "Выезд"},
+		{"31.02.2024", "Въезд"},
+		{"05.04.2024", "Выезд"},
+	}
+	if _, err := ParseBorderStatement(rows); err == nil || !strings.Contains(err.Error(), "строка 3") {
+		t.Errorf("expected an error for the row with a bad date, got %v", err)
+	}
+	rows = [][]string{{"Дата", "Направление"}, {"01.02.2024", "Выезд"}, {"", "Въезд"}, {"05.04.2024", "Выезд"}}
+	if _, err := ParseBorderStatement(rows); err == nil {
+		t.Error("expected an error for a crossing without a date")
+	}
+}
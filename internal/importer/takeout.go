@@ -0,0 +1,336 @@
+package importer
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// placesTSV holds the reference points used where the borders give no
+// answer.
+//
+//go:embed places.tsv
+var placesTSV string
+
+// place is a reference point: a city and its country.
+type place struct {
+	code     string
+	lat, lon float64
+}
+
+var (
+	placesOnce sync.Once
+	places     []place
+)
+
+func loadPlaces() []place {
+	placesOnce.Do(func() {
+		for _, line := range strings.Split(placesTSV, "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) < 3 || strings.HasPrefix(line, "#") {
+				continue
+			}
+			lat, errLat := strconv.ParseFloat(fields[1], 64)
+			lon, errLon := strconv.ParseFloat(fields[2], 64)
+			if errLat != nil || errLon != nil {
+				continue
+			}
+			places = append(places, place{code: fields[0], lat: lat, lon: lon})
+		}
+	})
+	return places
+}
+
+// maxPlaceDistance is how far, in kilometres, a coordinate outside every
+// border may be from a reference point and still count for its country: the
+// simplified coastline cuts off beaches and harbours. Farther ones (open
+// sea, in flight) are skipped.
+const maxPlaceDistance = 30
+
+// earthRadius is the mean radius of the Earth in kilometres.
+const earthRadius = 6371
+
+// countryAt returns the ISO code of the country around the coordinates. The
+// point is looked up in the country borders; off the coast it goes to the
+// nearest reference point. Borders are accurate to a few hundred metres.
+func countryAt(lat, lon float64) (string, bool) {
+	codes := loadBorders().countries(lat, lon)
+	if len(codes) == 1 {
+		return codes[0], true
+	}
+	best, bestDist := "", math.Inf(1)
+	for _, p := range loadPlaces() {
+		if len(codes) > 0 && !slices.Contains(codes, p.code) {
+			continue
+		}
+		if d := distance(lat, lon, p.lat, p.lon); d < bestDist {
+			best, bestDist = p.code, d
+		}
+	}
+	if len(codes) > 0 {
+		// на стыке двух упрощённых границ выбирается страна ближайшего города
+		if best == "" {
+			best = codes[0]
+		}
+		return best, true
+	}
+	return best, bestDist <= maxPlaceDistance
+}
+
+// distance is the great-circle distance in kilometres.
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// LocationHistory is the result of reading a Google Takeout export.
+type LocationHistory struct {
+	Periods []model.Period
+	// Points is the number of coordinates read.
+	Points int
+	// Unmatched is the number of coordinates too far from any country.
+	Unmatched int
+}
+
+// takeoutKeys are the top-level keys of the supported exports.
+var takeoutKeys = map[string]bool{"locations": true, "timelineObjects": true, "semanticSegments": true}
+
+// IsLocationHistory tells a Google Takeout export from a bot data file by
+// its first key.
+func IsLocationHistory(body []byte) bool {
+	dec := json.NewDecoder(bytes.NewReader(bytes.TrimPrefix(body, utf8BOM)))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+	key, err := dec.Token()
+	if err != nil {
+		return false
+	}
+	name, ok := key.(string)
+	return ok && takeoutKeys[name]
+}
+
+type e7Point struct {
+	LatitudeE7  *int64 `json:"latitudeE7"`
+	LongitudeE7 *int64 `json:"longitudeE7"`
+}
+
+type takeoutDuration struct {
+	StartTimestamp   string `json:"startTimestamp"`
+	EndTimestamp     string `json:"endTimestamp"`
+	StartTimestampMs string `json:"startTimestampMs"`
+	EndTimestampMs   string `json:"endTimestampMs"`
+}
+
+// takeoutFile covers Records.json, the monthly Semantic Location History
+// files and the newer on-device Timeline.json.
+type takeoutFile struct {
+	Locations []struct {
+		e7Point
+		Timestamp   string `json:"timestamp"`
+		TimestampMs string `json:"timestampMs"`
+	} `json:"locations"`
+	TimelineObjects []struct {
+		PlaceVisit *struct {
+			Location e7Point         `json:"location"`
+			Duration takeoutDuration `json:"duration"`
+		} `json:"placeVisit"`
+		ActivitySegment *struct {
+			StartLocation e7Point         `json:"startLocation"`
+			EndLocation   e7Point         `json:"endLocation"`
+			Duration      takeoutDuration `json:"duration"`
+		} `json:"activitySegment"`
+	} `json:"timelineObjects"`
+	SemanticSegments []struct {
+		StartTime string `json:"startTime"`
+		EndTime   string `json:"endTime"`
+		Visit     *struct {
+			TopCandidate struct {
+				PlaceLocation struct {
+					LatLng string `json:"latLng"`
+				} `json:"placeLocation"`
+			} `json:"topCandidate"`
+		} `json:"visit"`
+		TimelinePath []struct {
+			Point string `json:"point"`
+			Time  string `json:"time"`
+		} `json:"timelinePath"`
+	} `json:"semanticSegments"`
+}
+
+// fix is a coordinate at a moment.
+type fix struct {
+	at       time.Time
+	lat, lon float64
+}
+
+// minRunFixes is how many fixes in a row a country needs to count; single
+// stray fixes near a border or from a bad GPS reading are dropped.
+const minRunFixes = 2
+
+// ParseLocationHistory turns a Google Takeout location export into
+// country-level periods. A period runs from the first to the last day the
+// country was seen; days without data between two countries stay a gap.
+// Timestamps in UTC are shifted to the local date by the longitude.
+func ParseLocationHistory(body []byte) (LocationHistory, error) {
+	var history LocationHistory
+	var file takeoutFile
+	if err := json.Unmarshal(bytes.TrimPrefix(body, utf8BOM), &file); err != nil {
+		return history, fmt.Errorf("не удалось прочитать историю местоположений: %w", err)
+	}
+	fixes := file.fixes()
+	if len(fixes) == 0 {
+		return history, fmt.Errorf("в файле нет координат")
+	}
+	sort.SliceStable(fixes, func(i, j int) bool { return fixes[i].at.Before(fixes[j].at) })
+
+	type run struct {
+		code     string
+		from, to time.Time
+		fixes    int
+	}
+	var runs []run
+	cache := make(map[[2]int]string)
+	for _, f := range fixes {
+		history.Points++
+		// соседние точки почти всегда в одной стране, поиск кэшируется по сетке 0.001° (~100 м)
+		cell := [2]int{int(math.Round(f.lat * 1000)), int(math.Round(f.lon * 1000))}
+		code, ok := cache[cell]
+		if !ok {
+			if code, ok = countryAt(f.lat, f.lon); !ok {
+				code = ""
+			}
+			cache[cell] = code
+		}
+		if code == "" {
+			history.Unmatched++
+			continue
+		}
+		day := localDate(f)
+		if n := len(runs); n > 0 && runs[n-1].code == code {
+			runs[n-1].to = day
+			runs[n-1].fixes++
+			continue
+		}
+		runs = append(runs, run{code: code, from: day, to: day, fixes: 1})
+	}
+
+	var kept []run
+	for _, r := range runs {
+		if r.fixes < minRunFixes {
+			continue
+		}
+		if n := len(kept); n > 0 && kept[n-1].code == r.code {
+			kept[n-1].to = r.to
+			continue
+		}
+		kept = append(kept, r)
+	}
+	for _, r := range kept {
+		country, ok := utils.CountryByCode(r.code)
+		if !ok {
+			country = r.code
+		}
+		history.Periods = append(history.Periods, model.Period{
+			In:      utils.FormatDate(r.from),
+			Out:     utils.FormatDate(r.to),
+			Country: country,
+		})
+	}
+	return history, nil
+}
+
+func (file takeoutFile) fixes() []fix {
+	var fixes []fix
+	add := func(p e7Point, stamp, stampMs string) {
+		if p.LatitudeE7 == nil || p.LongitudeE7 == nil {
+			return
+		}
+		at, ok := takeoutTime(stamp, stampMs)
+		if !ok {
+			return
+		}
+		fixes = append(fixes, fix{at: at, lat: float64(*p.LatitudeE7) / 1e7, lon: float64(*p.LongitudeE7) / 1e7})
+	}
+	addLatLng := func(latLng, stamp string) {
+		lat, lon, ok := parseLatLng(latLng)
+		if !ok {
+			return
+		}
+		if at, ok := takeoutTime(stamp, ""); ok {
+			fixes = append(fixes, fix{at: at, lat: lat, lon: lon})
+		}
+	}
+
+	for _, l := range file.Locations {
+		add(l.e7Point, l.Timestamp, l.TimestampMs)
+	}
+	for _, obj := range file.TimelineObjects {
+		if v := obj.PlaceVisit; v != nil {
+			add(v.Location, v.Duration.StartTimestamp, v.Duration.StartTimestampMs)
+			add(v.Location, v.Duration.EndTimestamp, v.Duration.EndTimestampMs)
+		}
+		if a := obj.ActivitySegment; a != nil {
+			add(a.StartLocation, a.Duration.StartTimestamp, a.Duration.StartTimestampMs)
+			add(a.EndLocation, a.Duration.EndTimestamp, a.Duration.EndTimestampMs)
+		}
+	}
+	for _, seg := range file.SemanticSegments {
+		if v := seg.Visit; v != nil {
+			addLatLng(v.TopCandidate.PlaceLocation.LatLng, seg.StartTime)
+			addLatLng(v.TopCandidate.PlaceLocation.LatLng, seg.EndTime)
+		}
+		for _, p := range seg.TimelinePath {
+			addLatLng(p.Point, p.Time)
+		}
+	}
+	return fixes
+}
+
+// takeoutTime reads an RFC 3339 timestamp or, in older exports,
+// milliseconds since the epoch.
+func takeoutTime(stamp, stampMs string) (time.Time, bool) {
+	if stamp != "" {
+		t, err := time.Parse(time.RFC3339, stamp)
+		return t, err == nil
+	}
+	ms, err := strconv.ParseInt(stampMs, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms).UTC(), true
+}
+
+// parseLatLng reads the "48.8584°, 2.2945°" form of Timeline.json.
+func parseLatLng(value string) (float64, float64, bool) {
+	latText, lonText, ok := strings.Cut(strings.ReplaceAll(value, "°", ""), ",")
+	if !ok {
+		return 0, 0, false
+	}
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(latText), 64)
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(lonText), 64)
+	return lat, lon, errLat == nil && errLon == nil
+}
+
+// localDate is the calendar date at the fix. A timestamp with an offset
+// already is local; a UTC one is shifted by an hour per 15° of longitude.
+func localDate(f fix) time.Time {
+	at := f.at
+	if _, offset := at.Zone(); offset == 0 {
+		at = at.Add(time.Duration(math.Round(f.lon/15)) * time.Hour)
+	}
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+}
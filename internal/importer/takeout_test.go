@@ -0,0 +1,164 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestCountryAt(t *testing.T) {
+	cases := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"Москва", 55.75, 37.61, "RU"},
+		{"Тбилиси", 41.72, 44.79, "GE"},
+		{"Батуми", 41.65, 41.63, "GE"},
+		{"Ереван", 40.18, 44.51, "AM"},
+		{"Мюнхен", 48.14, 11.57, "DE"},
+		{"Зальцбург", 47.80, 13.04, "AT"},
+		{"Женева", 46.21, 6.15, "CH"},
+		{"Страсбург", 48.58, 7.75, "FR"},
+		{"Рим", 41.90, 12.49, "IT"},
+		{"Лиссабон", 38.72, -9.14, "PT"},
+		{"Алматы", 43.24, 76.89, "KZ"},
+		{"Дубай", 25.20, 55.27, "AE"},
+		{"Пхукет", 7.89, 98.40, "TH"},
+		{"Нью-Йорк", 40.71, -74.00, "US"},
+		{"Монреаль", 45.50, -73.57, "CA"},
+		// пограничные города, которые ближайший город относил к соседу
+		{"Курган", 55.44, 65.34, "RU"},
+		{"Выборг", 60.71, 28.75, "RU"},
+		{"Андижан", 40.78, 72.34, "UZ"},
+		{"Казбеги", 42.657, 44.644, "GE"},
+		{"Слубице", 52.345, 14.575, "PL"},
+		{"Франкфурт-на-Одере", 52.347, 14.551, "DE"},
+		{"Кель", 48.573, 7.815, "DE"},
+		{"Торнио", 65.85, 24.18, "FI"},
+		{"Хапаранда", 65.836, 24.12, "SE"},
+		{"Нарва", 59.375, 28.16, "EE"},
+		{"Ивангород", 59.37, 28.22, "RU"},
+		{"Благовещенск", 50.27, 127.53, "RU"},
+		{"Хэйхэ", 50.245, 127.49, "CN"},
+		{"Эль-Пасо", 31.76, -106.49, "US"},
+		{"Сьюдад-Хуарес", 31.69, -106.42, "MX"},
+		{"Ватикан", 41.9029, 12.4534, "VA"},
+		{"Подгорица", 42.44, 19.26, "ME"},
+		{"Скопье", 41.99, 21.43, "MK"},
+	}
+	for _, c := range cases {
+		got, ok := countryAt(c.lat, c.lon)
+		if !ok || got != c.want {
+			t.Errorf("%s: got %q %v, want %q", c.name, got, ok, c.want)
+		}
+	}
+	for _, p := range [][2]float64{{20, -45}, {0, -160}} {
+		if code, ok := countryAt(p[0], p[1]); ok {
+			t.Errorf("open ocean %v matched %s", p, code)
+		}
+	}
+}
+
+func TestPlacesData(t *testing.T) {
+	list := loadPlaces()
+	if len(list) < 500 {
+		t.Fatalf("only %d reference points loaded", len(list))
+	}
+	for _, p := range list {
+		if len(p.code) != 2 || p.lat < -90 || p.lat > 90 || p.lon < -180 || p.lon > 180 {
+			t.Errorf("bad reference point %+v", p)
+		}
+	}
+}
+
+const records = `{
+  "locations": [
+    {"latitudeE7": 557558000, "longitudeE7": 376173000, "timestamp": "2024-03-01T08:00:00.000Z"},
+    {"latitudeE7": 557558000, "longitudeE7": 376173000, "timestamp": "2024-03-02T04:00:00Z"},
+    {"latitudeE7": 417151000, "longitudeE7": 447827000, "timestampMs": "1709359200000"},
+    {"latitudeE7": 417151000, "longitudeE7": 447827000, "timestamp": "2024-03-05T21:30:00Z"},
+    {"latitudeE7": 401792000, "longitudeE7": 445126000, "timestamp": "2024-03-06T10:00:00Z"},
+    {"latitudeE7": 417151000, "longitudeE7": 447827000, "timestamp": "2024-03-07T10:00:00Z"},
+    {"latitudeE7": 417151000, "longitudeE7": 447827000, "timestamp": "2024-03-08T10:00:00Z"},
+    {"latitudeE7": 200000000, "longitudeE7": -450000000, "timestamp": "2024-03-08T12:00:00Z"}
+  ]
+}`
+
+func TestParseLocationHistoryRecords(t *testing.T) {
+	if !IsLocationHistory([]byte(records)) {
+		t.Fatal("Records.json not recognised")
+	}
+	got, err := ParseLocationHistory([]byte(records))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-03-05 21:30 UTC в Тбилиси — это уже 6 марта; одиночная точка в
+	// Ереване отбрасывается как выброс
+	want := []model.Period{
+		{In: "01.03.2024", Out: "02.03.2024", Country: "Россия"},
+		{In: "02.03.2024", Out: "08.03.2024", Country: "Грузия"},
+	}
+	if !reflect.DeepEqual(got.Periods, want) {
+		t.Errorf("got %+v, want %+v", got.Periods, want)
+	}
+	if got.Points != 8 || got.Unmatched != 1 {
+		t.Errorf("points %d, unmatched %d", got.Points, got.Unmatched)
+	}
+}
+
+const semantic = `{
+  "timelineObjects": [
+    {"placeVisit": {"location": {"latitudeE7": 415025000, "longitudeE7": 443000000, "name": "Hotel"},
+      "duration": {"startTimestamp": "2023-07-10T12:00:00Z", "endTimestamp": "2023-07-14T07:00:00Z"}}},
+    {"activitySegment": {"startLocation": {"latitudeE7": 417151000, "longitudeE7": 447827000},
+      "endLocation": {"latitudeE7": 401792000, "longitudeE7": 445126000},
+      "duration": {"startTimestamp": "2023-07-14T08:00:00Z", "endTimestamp": "2023-07-14T14:00:00Z"}}},
+    {"placeVisit": {"location": {"latitudeE7": 401792000, "longitudeE7": 445126000},
+      "duration": {"startTimestamp": "2023-07-14T15:00:00Z", "endTimestamp": "2023-07-20T09:00:00Z"}}}
+  ]
+}`
+
+func TestParseLocationHistorySemantic(t *testing.T) {
+	got, err := ParseLocationHistory([]byte(semantic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Period{
+		{In: "10.07.2023", Out: "14.07.2023", Country: "Грузия"},
+		{In: "14.07.2023", Out: "20.07.2023", Country: "Армения"},
+	}
+	if !reflect.DeepEqual(got.Periods, want) {
+		t.Errorf("got %+v, want %+v", got.Periods, want)
+	}
+}
+
+func TestParseLocationHistoryTimeline(t *testing.T) {
+	body := `{"semanticSegments": [
+    {"startTime": "2024-05-01T10:00:00.000+06:00", "endTime": "2024-05-03T23:30:00.000+06:00",
+      "visit": {"topCandidate": {"placeLocation": {"latLng": "43.2380°, 76.8829°"}}}},
+    {"startTime": "2024-05-04T00:00:00.000+06:00", "endTime": "2024-05-04T06:00:00.000+06:00",
+      "timelinePath": [{"point": "43.24°, 76.89°", "time": "2024-05-04T01:00:00.000+06:00"}]}
+  ]}`
+	got, err := ParseLocationHistory([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Period{{In: "01.05.2024", Out: "04.05.2024", Country: "Казахстан"}}
+	if !reflect.DeepEqual(got.Periods, want) {
+		t.Errorf("got %+v, want %+v", got.Periods, want)
+	}
+}
+
+func TestIsLocationHistory(t *testing.T) {
+	for body, want := range map[string]bool{
+		`{"periods": [], "current": "01.01.2024"}`: false,
+		`{"timelineObjects": []}`:                  true,
+		`not json`:                                 false,
+	} {
+		if got := IsLocationHistory([]byte(body)); got != want {
+			t.Errorf("%s: got %v, want %v", body, got, want)
+		}
+	}
+}
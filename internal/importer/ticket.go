@@ -0,0 +1,491 @@
+package importer
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+	"unicode/utf8"
+)
+
+// airportsTSV maps IATA airport codes to countries and time zones.
+//
+//go:embed airports.tsv
+var airportsTSV string
+
+// airport is an entry of the embedded airport table.
+type airport struct {
+	code string // ISO country code
+	city string
+	zone string // IANA time zone
+}
+
+var (
+	airportsOnce sync.Once
+	airports     map[string]airport
+)
+
+func loadAirports() map[string]airport {
+	airportsOnce.Do(func() {
+		airports = make(map[string]airport)
+		for _, line := range strings.Split(airportsTSV, "\n") {
+			fields := strings.Split(line, "\t")
+			if len(fields) < 3 || strings.HasPrefix(line, "#") {
+				continue
+			}
+			a := airport{code: fields[1], city: fields[2]}
+			if len(fields) > 3 {
+				a.zone = fields[3]
+			}
+			airports[fields[0]] = a
+		}
+	})
+	return airports
+}
+
+// Flight is one flight segment of an itinerary. Dates are "02.01.2006" and
+// times local "15:04"; times are empty when the text does not give them.
+type Flight struct {
+	Number string
+	// From and To are IATA airport codes.
+	From, To   string
+	DepartDate string
+	DepartTime string
+	ArriveDate string
+	ArriveTime string
+}
+
+// Itinerary is the result of reading an e-ticket or a booking confirmation.
+type Itinerary struct {
+	// Flights are ordered by departure.
+	Flights []Flight
+	// Periods are the proposed stays between an arrival and the next
+	// departure, plus an open stay after a one-way trip.
+	Periods []model.Period
+	// Transits describe connections too short to count as a stay.
+	Transits []string
+}
+
+// transitHours is the longest connection treated as a transit when both
+// times are known; without times a connection on the same day is a transit.
+const transitHours = 12
+
+// Patterns of the parts of an itinerary. Matches are checked against the
+// neighbouring characters, since \b does not work next to Cyrillic letters.
+var (
+	flightPattern  = regexp.MustCompile(`\b([A-Z]{2}|[A-Z][0-9]|[0-9][A-Z])\s?-?(\d{1,4})\b`)
+	airportPattern = regexp.MustCompile(`\b[A-Z]{3}(?:[A-Z]{3})?\b`)
+	numericDate    = regexp.MustCompile(`(\d{1,2})[./](\d{1,2})[./](\d{4}|\d{2})`)
+	isoDate        = regexp.MustCompile(`(\d{4})-(\d{1,2})-(\d{1,2})`)
+	dayMonthDate   = regexp.MustCompile(`(\d{1,2})\s?([A-Za-zА-Яа-яЁё]{3,})\.?(?:,?\s?(\d{4})|(\d{2}))?`)
+	monthDayDate   = regexp.MustCompile(`([A-Za-z]{3,})\.?\s(\d{1,2}),?\s(\d{4})`)
+	itineraryClock = regexp.MustCompile(`(\d{1,2}):(\d{2})(?:\s?([AaPp])\.?[Mm]\.?\b)?(?:\s?\(?\+([12])\)?)?`)
+	monthPrefixes  = map[string]time.Month{
+		"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+		"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+		"sep": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+		"янв": time.January, "фев": time.February, "мар": time.March, "апр": time.April,
+		"мая": time.May, "май": time.May, "июн": time.June, "июл": time.July, "авг": time.August,
+		"сен": time.September, "окт": time.October, "ноя": time.November, "дек": time.December,
+	}
+)
+
+// itineraryToken is a flight number, an airport code, a date or a time found
+// at pos in the text.
+type itineraryToken struct {
+	pos   int
+	value string
+	// year (0 when not given), month and day of a date
+	year, month, day int
+	// shift is the "+1" day marker after an arrival time
+	shift int
+}
+
+// ParseItinerary reads flight segments from a forwarded e-ticket or booking
+// confirmation and proposes stays between them. Every two IATA airport codes
+// make a segment; flight numbers, dates and "15:04" times are attached to the
+// segment they precede or follow, as the first of each kind shows. A date
+// without a year is placed nearest to the previous flight, the first one
+// nearest to now.
+func ParseItinerary(text string, now time.Time) (Itinerary, error) {
+	var result Itinerary
+	type segment struct{ from, to itineraryToken }
+	var segments []segment
+	var pending *itineraryToken
+	for _, code := range findAirports(text) {
+		switch {
+		case pending == nil:
+			c := code
+			pending = &c
+		case code.value == pending.value:
+			// the departure airport named twice
+		default:
+			segments = append(segments, segment{*pending, code})
+			pending = nil
+		}
+	}
+	if len(segments) == 0 {
+		return result, fmt.Errorf("в тексте нет кодов аэропортов вылета и прилёта")
+	}
+
+	numbers, dates, clocks := findFlightNumbers(text), findDates(text), findClocks(text)
+	pick := func(tokens []itineraryToken, k int) []itineraryToken {
+		if len(tokens) == 0 {
+			return nil
+		}
+		lo, hi := segments[k].from.pos, len(text)
+		if k+1 < len(segments) {
+			hi = segments[k+1].from.pos
+		}
+		if tokens[0].pos < segments[0].from.pos {
+			lo, hi = -1, segments[k].to.pos
+			if k > 0 {
+				lo = segments[k-1].to.pos
+			}
+		}
+		var picked []itineraryToken
+		for _, t := range tokens {
+			if t.pos > lo && t.pos < hi {
+				picked = append(picked, t)
+			}
+		}
+		return picked
+	}
+
+	ref := now
+	for k, seg := range segments {
+		segDates := pick(dates, k)
+		if len(segDates) == 0 {
+			continue
+		}
+		depart, ok := resolveDate(segDates[0], ref)
+		if !ok {
+			continue
+		}
+		f := Flight{From: seg.from.value, To: seg.to.value, DepartDate: utils.FormatDate(depart)}
+		// a number written before the route is the one nearest to it;
+		// otherwise the first one after the route
+		if n := pick(numbers, k); len(n) > 0 {
+			f.Number = n[0].value
+			for _, t := range n {
+				if t.pos < seg.from.pos {
+					f.Number = t.value
+				}
+			}
+		}
+		arrive := depart
+		segClocks := pick(clocks, k)
+		if len(segClocks) > 0 {
+			f.DepartTime = segClocks[0].value
+		}
+		if len(segClocks) > 1 {
+			f.ArriveTime = segClocks[1].value
+		}
+		switch {
+		case len(segDates) > 1:
+			if d, ok := resolveDate(segDates[1], depart); ok {
+				arrive = d
+			}
+		case len(segClocks) > 1 && segClocks[1].shift > 0:
+			arrive = depart.AddDate(0, 0, segClocks[1].shift)
+		case f.ArriveTime != "" && f.DepartTime != "":
+			arrive = arrivalDate(f, depart)
+		}
+		f.ArriveDate = utils.FormatDate(arrive)
+		result.Flights = append(result.Flights, f)
+		ref = arrive
+	}
+	if len(result.Flights) == 0 {
+		return result, fmt.Errorf("в тексте нет дат рейсов")
+	}
+	sort.SliceStable(result.Flights, func(i, j int) bool {
+		a, b := result.Flights[i], result.Flights[j]
+		return flightMoment(a.DepartDate, a.DepartTime, a.From).Before(flightMoment(b.DepartDate, b.DepartTime, b.From))
+	})
+
+	home := airportCountry(result.Flights[0].From)
+	for i, f := range result.Flights {
+		country := airportCountry(f.To)
+		if i+1 == len(result.Flights) {
+			if country != home {
+				stay := model.Period{In: f.ArriveDate, InTime: f.ArriveTime, Country: country}
+				result.Periods = append(result.Periods, stayTimeZone(stay, f.To, ""))
+			}
+			break
+		}
+		next := result.Flights[i+1]
+		if isTransit(f, next) {
+			result.Transits = append(result.Transits, fmt.Sprintf("%s (%s), %s", loadAirports()[f.To].city, f.To, f.ArriveDate))
+			continue
+		}
+		stay := model.Period{
+			In:      f.ArriveDate,
+			InTime:  f.ArriveTime,
+			Out:     next.DepartDate,
+			OutTime: next.DepartTime,
+			Country: country,
+		}
+		result.Periods = append(result.Periods, stayTimeZone(stay, f.To, next.From))
+	}
+	return result, nil
+}
+
+// FormatFlight is the one-line view of a flight.
+func FormatFlight(f Flight) string {
+	depart := strings.TrimSpace(f.DepartDate + " " + f.DepartTime)
+	arrive := strings.TrimSpace(f.ArriveDate + " " + f.ArriveTime)
+	line := fmt.Sprintf("%s → %s: %s — %s", f.From, f.To, depart, arrive)
+	if f.Number != "" {
+		line = f.Number + " " + line
+	}
+	return line
+}
+
+// airportCountry returns the country of an airport by name, or its ISO code
+// when the country is not in the list.
+func airportCountry(code string) string {
+	iso := loadAirports()[code].code
+	if country, ok := utils.CountryByCode(iso); ok {
+		return country
+	}
+	return iso
+}
+
+// isTransit reports whether the connection between the two flights is too
+// short to be a stay in the country.
+func isTransit(arrival, departure Flight) bool {
+	if arrival.ArriveTime != "" && departure.DepartTime != "" {
+		gap := flightMoment(departure.DepartDate, departure.DepartTime, departure.From).Sub(flightMoment(arrival.ArriveDate, arrival.ArriveTime, arrival.To))
+		return gap < transitHours*time.Hour
+	}
+	return arrival.ArriveDate == departure.DepartDate
+}
+
+// flightMoment is the local date and time at the airport as an instant.
+func flightMoment(date, clock, airportCode string) time.Time {
+	day, _ := utils.ParseDate(date)
+	moment := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, airportLocation(airportCode))
+	if t, err := time.Parse("15:04", clock); err == nil {
+		moment = moment.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute)
+	}
+	return moment
+}
+
+// airportLocation is the time zone of the airport, UTC when unknown.
+func airportLocation(code string) *time.Location {
+	loc, err := time.LoadLocation(loadAirports()[code].zone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// arrivalDate guesses the arrival date when only the times are given: the
+// first of the day before, the same day and the next day on which the
+// arrival comes after the departure in the airports' own time zones, so a
+// flight across the date line may land on the same or the previous date.
+func arrivalDate(f Flight, depart time.Time) time.Time {
+	leave := flightMoment(f.DepartDate, f.DepartTime, f.From)
+	for shift := -1; shift <= 1; shift++ {
+		day := depart.AddDate(0, 0, shift)
+		if flightMoment(utils.FormatDate(day), f.ArriveTime, f.To).After(leave) {
+			return day
+		}
+	}
+	return depart.AddDate(0, 0, 1)
+}
+
+// stayTimeZone gives a stay with clock times the time zone of the arrival
+// airport. A period has one zone, so a departure from another zone of the
+// same country is converted to it.
+func stayTimeZone(p model.Period, arrival, departure string) model.Period {
+	zone := loadAirports()[arrival].zone
+	if zone == "" || (p.InTime == "" && p.OutTime == "") {
+		return p
+	}
+	p.TimeZone = zone
+	if p.OutTime != "" && departure != "" && loadAirports()[departure].zone != zone {
+		at := flightMoment(p.Out, p.OutTime, departure).In(airportLocation(arrival))
+		p.Out, p.OutTime = utils.FormatDate(at), at.Format("15:04")
+	}
+	return p
+}
+
+// findAirports returns known IATA codes in order. Two codes written
+// together, as in "SVOIST", are split.
+func findAirports(text string) []itineraryToken {
+	known := loadAirports()
+	var tokens []itineraryToken
+	for _, m := range airportPattern.FindAllStringIndex(text, -1) {
+		word := text[m[0]:m[1]]
+		if len(word) == 3 {
+			if _, ok := known[word]; ok {
+				tokens = append(tokens, itineraryToken{pos: m[0], value: word})
+			}
+			continue
+		}
+		_, okFrom := known[word[:3]]
+		_, okTo := known[word[3:]]
+		if okFrom && okTo {
+			tokens = append(tokens,
+				itineraryToken{pos: m[0], value: word[:3]},
+				itineraryToken{pos: m[0] + 3, value: word[3:]})
+		}
+	}
+	return tokens
+}
+
+// findFlightNumbers returns flight numbers such as "SU 2130" in order.
+func findFlightNumbers(text string) []itineraryToken {
+	var tokens []itineraryToken
+	for _, m := range flightPattern.FindAllStringSubmatchIndex(text, -1) {
+		tokens = append(tokens, itineraryToken{pos: m[0], value: text[m[2]:m[3]] + " " + text[m[4]:m[5]]})
+	}
+	return tokens
+}
+
+// findDates returns dates written as 15.03.2024, 2024-03-15, 15 марта 2024,
+// 15MAR24 or Mar 15, 2024, in order.
+func findDates(text string) []itineraryToken {
+	var tokens []itineraryToken
+	add := func(pos int, year, month, day string) {
+		t := itineraryToken{pos: pos}
+		t.year, _ = strconv.Atoi(year)
+		t.month, _ = strconv.Atoi(month)
+		t.day, _ = strconv.Atoi(day)
+		if t.year > 0 && t.year < 100 {
+			t.year += 2000
+		}
+		if t.month >= 1 && t.month <= 12 && t.day >= 1 && t.day <= 31 {
+			tokens = append(tokens, t)
+		}
+	}
+	group := func(m []int, n int) string {
+		if m[2*n] < 0 {
+			return ""
+		}
+		return text[m[2*n]:m[2*n+1]]
+	}
+
+	for _, m := range numericDate.FindAllStringSubmatchIndex(text, -1) {
+		if separated(text, m[0], m[1]) {
+			add(m[0], group(m, 3), group(m, 2), group(m, 1))
+		}
+	}
+	for _, m := range isoDate.FindAllStringSubmatchIndex(text, -1) {
+		if separated(text, m[0], m[1]) {
+			add(m[0], group(m, 1), group(m, 2), group(m, 3))
+		}
+	}
+	for _, m := range dayMonthDate.FindAllStringSubmatchIndex(text, -1) {
+		month, ok := monthNumber(group(m, 2))
+		if !ok || gluedBefore(text, m[0]) {
+			continue
+		}
+		year := group(m, 3) + group(m, 4)
+		if gluedAfter(text, m[1]) {
+			year = ""
+		}
+		add(m[0], year, strconv.Itoa(month), group(m, 1))
+	}
+	for _, m := range monthDayDate.FindAllStringSubmatchIndex(text, -1) {
+		if month, ok := monthNumber(group(m, 1)); ok && separated(text, m[0], m[1]) {
+			add(m[0], group(m, 3), strconv.Itoa(month), group(m, 2))
+		}
+	}
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].pos < tokens[j].pos })
+	return tokens
+}
+
+// findClocks returns "15:04" times in order; "7:05 PM" is converted to 24
+// hours and a following "+1" is kept as the day shift.
+func findClocks(text string) []itineraryToken {
+	var tokens []itineraryToken
+	for _, m := range itineraryClock.FindAllStringSubmatchIndex(text, -1) {
+		if !separated(text, m[0], m[1]) {
+			continue
+		}
+		hour, _ := strconv.Atoi(text[m[2]:m[3]])
+		minute, _ := strconv.Atoi(text[m[4]:m[5]])
+		if m[6] >= 0 {
+			if hour < 1 || hour > 12 {
+				continue
+			}
+			hour %= 12
+			if strings.EqualFold(text[m[6]:m[7]], "p") {
+				hour += 12
+			}
+		}
+		if hour > 23 || minute > 59 {
+			continue
+		}
+		t := itineraryToken{pos: m[0], value: fmt.Sprintf("%02d:%02d", hour, minute)}
+		if m[8] >= 0 {
+			t.shift, _ = strconv.Atoi(text[m[8]:m[9]])
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens
+}
+
+// separated reports whether text[start:end] is glued neither to digits nor
+// to a time before it, nor to digits after it.
+func separated(text string, start, end int) bool {
+	return !gluedBefore(text, start) && !gluedAfter(text, end)
+}
+
+func gluedBefore(text string, pos int) bool {
+	r, _ := utf8.DecodeLastRuneInString(text[:pos])
+	return (r >= '0' && r <= '9') || r == ':'
+}
+
+func gluedAfter(text string, pos int) bool {
+	r, _ := utf8.DecodeRuneInString(text[pos:])
+	return r >= '0' && r <= '9'
+}
+
+// monthNumber recognises English and Russian month names by their first
+// three letters.
+func monthNumber(word string) (int, bool) {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) < 3 {
+		return 0, false
+	}
+	month, ok := monthPrefixes[string(runes[:3])]
+	return int(month), ok
+}
+
+// resolveDate turns a date token into a date; a missing year is chosen so
+// that the date is nearest to ref.
+func resolveDate(t itineraryToken, ref time.Time) (time.Time, bool) {
+	build := func(year int) (time.Time, bool) {
+		d := time.Date(year, time.Month(t.month), t.day, 0, 0, 0, 0, time.UTC)
+		return d, d.Day() == t.day
+	}
+	if t.year != 0 {
+		return build(t.year)
+	}
+	ref = time.Date(ref.Year(), ref.Month(), ref.Day(), 0, 0, 0, 0, time.UTC)
+	var best time.Time
+	found := false
+	for year := ref.Year() - 1; year <= ref.Year()+1; year++ {
+		d, ok := build(year)
+		if ok && (!found || absDuration(d.Sub(ref)) < absDuration(best.Sub(ref))) {
+			best, found = d, true
+		}
+	}
+	return best, found
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
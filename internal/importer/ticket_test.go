@@ -0,0 +1,180 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestAirportsData(t *testing.T) {
+	list := loadAirports()
+	if len(list) < 500 {
+		t.Fatalf("only %d airports loaded", len(list))
+	}
+	for code, a := range list {
+		if len(code) != 3 || len(a.code) != 2 || a.city == "" {
+			t.Errorf("bad airport %s %+v", code, a)
+		}
+		if _, err := time.LoadLocation(a.zone); err != nil || a.zone == "" {
+			t.Errorf("bad time zone of %s: %q", code, a.zone)
+		}
+	}
+	for code, want := range map[string]string{"SVO": "Россия", "IST": "Турция", "TBS": "Грузия", "DXB": "ОАЭ"} {
+		if got := airportCountry(code); got != want {
+			t.Errorf("%s: got %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestParseItineraryRussian(t *testing.T) {
+	text := `Рейс SU 2130
+Вылет: 15.03.2024 10:20, Москва, Шереметьево (SVO)
+Прилёт: 15.03.2024 13:05, Стамбул (IST)
+
+Рейс TK 378
+Вылет: 20.03.2024 08:00, Стамбул (IST)
+Прилёт: 20.03.2024 11:30, Тбилиси (TBS)`
+	got, err := ParseItinerary(text, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFlights := []Flight{
+		{Number: "SU 2130", From: "SVO", To: "IST", DepartDate: "15.03.2024", DepartTime: "10:20", ArriveDate: "15.03.2024", ArriveTime: "13:05"},
+		{Number: "TK 378", From: "IST", To: "TBS", DepartDate: "20.03.2024", DepartTime: "08:00", ArriveDate: "20.03.2024", ArriveTime: "11:30"},
+	}
+	if !reflect.DeepEqual(got.Flights, wantFlights) {
+		t.Errorf("flights: got %+v, want %+v", got.Flights, wantFlights)
+	}
+	// поездка в одну сторону: после последнего прилёта пребывание открыто
+	wantPeriods := []model.Period{
+		{In: "15.03.2024", InTime: "13:05", Out: "20.03.2024", OutTime: "08:00", Country: "Турция", TimeZone: "Europe/Istanbul"},
+		{In: "20.03.2024", InTime: "11:30", Country: "Грузия", TimeZone: "Asia/Tbilisi"},
+	}
+	if !reflect.DeepEqual(got.Periods, wantPeriods) {
+		t.Errorf("periods: got %+v, want %+v", got.Periods, wantPeriods)
+	}
+}
+
+func TestParseItineraryTransitAndNewYear(t *testing.T) {
+	text := `28 декабря
+23:10 Москва, SVO
+05:30 +1 Дубай, DXB
+Emirates, EK 132
+
+29 декабря
+09:00 Дубай, DXB
+14:20 Бангкок, BKK
+Emirates, EK 372
+
+8 января
+16:00 Бангкок, BKK
+22:30 Москва, SVO
+Аэрофлот, SU 271`
+	got, err := ParseItinerary(text, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Flights) != 3 {
+		t.Fatalf("got %d flights: %+v", len(got.Flights), got.Flights)
+	}
+	if f := got.Flights[0]; f.Number != "EK 132" || f.ArriveDate != "29.12.2024" || f.ArriveTime != "05:30" {
+		t.Errorf("first flight %+v", f)
+	}
+	if f := got.Flights[2]; f.Number != "SU 271" || f.DepartDate != "08.01.2025" {
+		t.Errorf("last flight %+v", f)
+	}
+	// пересадка в Дубае не считается, после возвращения домой периода нет
+	want := []model.Period{{In: "29.12.2024", InTime: "14:20", Out: "08.01.2025", OutTime: "16:00", Country: "Таиланд", TimeZone: "Asia/Bangkok"}}
+	if !reflect.DeepEqual(got.Periods, want) {
+		t.Errorf("periods: got %+v, want %+v", got.Periods, want)
+	}
+	if !reflect.DeepEqual(got.Transits, []string{"Dubai (DXB), 29.12.2024"}) {
+		t.Errorf("transits: %v", got.Transits)
+	}
+}
+
+func TestParseItineraryDateLine(t *testing.T) {
+	// на восток через линию перемены дат прилёт в тот же день «раньше» вылета
+	text := `Рейс SU 262
+Вылет: 01.03.2024 19:00, Москва (SVO)
+Прилёт: 02.03.2024 11:00, Токио (NRT)
+
+Рейс JL 782
+Вылет: 15.03.2024 18:00, Токио (NRT)
+Прилёт: 06:00, Гонолулу (HNL)`
+	got, err := ParseItinerary(text, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Period{
+		{In: "02.03.2024", InTime: "11:00", Out: "15.03.2024", OutTime: "18:00", Country: "Япония", TimeZone: "Asia/Tokyo"},
+		{In: "15.03.2024", InTime: "06:00", Country: "США", TimeZone: "Pacific/Honolulu"},
+	}
+	if !reflect.DeepEqual(got.Periods, want) {
+		t.Fatalf("periods: got %+v, want %+v", got.Periods, want)
+	}
+	left, _ := utils.ParseDateTime(want[0].Out, want[0].OutTime, want[0].TimeZone)
+	landed, _ := utils.ParseDateTime(want[1].In, want[1].InTime, want[1].TimeZone)
+	if !landed.After(left) {
+		t.Errorf("arrival %v is not after departure %v", landed, left)
+	}
+}
+
+func TestParseItineraryOpenJaw(t *testing.T) {
+	// прилёт в Нью-Йорк и вылет из Лос-Анджелеса: время выезда переводится
+	// в часовой пояс въезда
+	text := `SU 100 10.05.2024 SVO 10:00 JFK 13:00
+JL 61 25.05.2024 LAX 23:30 NRT 05:00 +2`
+	got, err := ParseItinerary(text, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Periods) != 2 {
+		t.Fatalf("periods: %+v", got.Periods)
+	}
+	us := got.Periods[0]
+	if us.TimeZone != "America/New_York" || us.Out != "26.05.2024" || us.OutTime != "02:30" {
+		t.Errorf("stay in the US %+v", us)
+	}
+}
+
+func TestParseItineraryGDS(t *testing.T) {
+	text := ` 1 SU2130 Y 15MAR 5 SVOIST HK1 1020 1305
+ 2 SU2131 Y 22MAR 5 ISTSVO HK1 1405 1750`
+	got, err := ParseItinerary(text, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Period{{In: "15.03.2024", Out: "22.03.2024", Country: "Турция"}}
+	if !reflect.DeepEqual(got.Periods, want) {
+		t.Errorf("periods: got %+v, want %+v", got.Periods, want)
+	}
+	if got.Flights[1].Number != "SU 2131" {
+		t.Errorf("flight number %q", got.Flights[1].Number)
+	}
+}
+
+func TestParseItineraryEnglish(t *testing.T) {
+	text := `Flight LH 2548, Fri, Mar 15, 2024
+Depart 7:05 PM Frankfurt (FRA)
+Arrive 1:40 AM Tbilisi (TBS)`
+	got, err := ParseItinerary(text, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Flight{Number: "LH 2548", From: "FRA", To: "TBS", DepartDate: "15.03.2024", DepartTime: "19:05", ArriveDate: "16.03.2024", ArriveTime: "01:40"}
+	if len(got.Flights) != 1 || got.Flights[0] != want {
+		t.Errorf("got %+v, want %+v", got.Flights, want)
+	}
+}
+
+func TestParseItineraryNotATicket(t *testing.T) {
+	for _, text := range []string{"привет", "Летим SVO-IST", "Курс USD на 15.03.2024"} {
+		if _, err := ParseItinerary(text, time.Now()); err == nil {
+			t.Errorf("%q: expected an error", text)
+		}
+	}
+}
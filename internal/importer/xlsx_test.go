@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// buildXLSX packs the given parts into a minimal workbook.
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	testWorkbook = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Выписка" sheetId="1" r:id="rId7"/></sheets></workbook>`
+	testRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/data.xml"/>
+</Relationships>`
+	testShared = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Дата</t></si><si><r><t>Напра</t></r><r><t>вление</t></r></si><si><t>Выезд</t></si>
+</sst>`
+	testSheet = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2"><v>45323.5</v></c><c r="C2" t="s"><v>2</v></c><c r="D2" t="inlineStr"><is><t>Турция</t></is></c></row>
+</sheetData></worksheet>`
+)
+
+func TestReadXLSX(t *testing.T) {
+	body := buildXLSX(t, map[string]string{
+		"xl/workbook.xml":            testWorkbook,
+		"xl/_rels/workbook.xml.rels": testRels,
+		"xl/sharedStrings.xml":       testShared,
+		"xl/worksheets/data.xml":     testSheet,
+	})
+	rows, err := ReadXLSX(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Дата", "", "Направление"},
+		{"45323.5", "", "Выезд", "Турция"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("got %q, want %q", rows, want)
+	}
+}
+
+func TestReadXLSXNotExcel(t *testing.T) {
+	if _, err := ReadXLSX([]byte("%PDF-1.7")); err == nil {
+		t.Error("expected error for non-xlsx data")
+	}
+}
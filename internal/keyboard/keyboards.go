@@ -16,6 +16,19 @@ func BuildBackToMenu() tgbotapi.ReplyKeyboardMarkup {
 	return markup
 }
 
+// BuildReportMenu returns keyboard shown under a report; it offers to fill
+// gaps when the report has unknown days.
+func BuildReportMenu(fillGaps bool) tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	if fillGaps {
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🧩 Заполнить пробелы")))
+	}
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад в меню")))
+	markup := tgbotapi.NewReplyKeyboard(rows...)
+	markup.ResizeKeyboard = true
+	return markup
+}
+
 // BuildBack returns a keyboard with a single "Назад" button.
 func BuildBack() tgbotapi.ReplyKeyboardMarkup {
 	markup := tgbotapi.NewReplyKeyboard(
@@ -33,15 +46,21 @@ func BuildMainMenu(s *model.Session) tgbotapi.ReplyKeyboardMarkup {
 	if s.IsEmpty() {
 		rows = [][]tgbotapi.KeyboardButton{
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📎 Загрузить файл")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🛂 Пересечения границ")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("ℹ️ Помощь")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📖 Команды")),
 		}
 	} else {
 		rows = [][]tgbotapi.KeyboardButton{
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📋 Показать текущие данные")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🛂 Пересечения границ")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📊 Отчёт")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📅 Отчёт на заданную дату")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📆 Статус по годам")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("⚖️ Правило расчёта")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🇪🇺 Шенген 90/180")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📎 Загрузить новый файл")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📥 Дополнить из файла")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🗑 Сбросить")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("ℹ️ Помощь")),
 			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📖 Команды")),
@@ -59,6 +78,7 @@ func BuildPeriodsMenu() tgbotapi.ReplyKeyboardMarkup {
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("✏️ Отредактировать период")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("➕ Добавить период")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🗑 Удалить период")),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🧹 Упорядочить периоды")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📊 Отчёт")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад в меню")),
 	)
@@ -66,6 +86,25 @@ func BuildPeriodsMenu() tgbotapi.ReplyKeyboardMarkup {
 	return markup
 }
 
+// BuildCrossingsMenu returns keyboard for border crossing log actions.
+func BuildCrossingsMenu(hasCrossings bool) tgbotapi.ReplyKeyboardMarkup {
+	rows := [][]tgbotapi.KeyboardButton{
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("➕ Добавить пересечения")),
+	}
+	if hasCrossings {
+		rows = append(rows,
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("✏️ Изменить пересечение")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🗑 Удалить пересечения")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📋 Показать текущие данные")),
+			tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📊 Отчёт")),
+		)
+	}
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад в меню")))
+	markup := tgbotapi.NewReplyKeyboard(rows...)
+	markup.ResizeKeyboard = true
+	return markup
+}
+
 // BuildAddPeriodMenu returns keyboard for choosing type of period to add.
 func BuildAddPeriodMenu() tgbotapi.ReplyKeyboardMarkup {
 	markup := tgbotapi.NewReplyKeyboard(
@@ -84,12 +123,27 @@ func BuildEditFieldMenu() tgbotapi.ReplyKeyboardMarkup {
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📅 Изменить дату въезда (in)")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📆 Изменить дату выезда (out)")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🌍 Изменить страну")),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("📍 Изменить регион")),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🕐 Изменить время и часовой пояс")),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🏷 Изменить цель поездки")),
 		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад")),
 	)
 	markup.ResizeKeyboard = true
 	return markup
 }
 
+// BuildPurposeMenu returns keyboard with one button per period purpose.
+func BuildPurposeMenu(titles []string) tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	for _, title := range titles {
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(title)))
+	}
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад")))
+	markup := tgbotapi.NewReplyKeyboard(rows...)
+	markup.ResizeKeyboard = true
+	return markup
+}
+
 // BuildResolveOptions returns keyboard for conflict resolution with a move option.
 func BuildResolveOptions(move string) tgbotapi.ReplyKeyboardMarkup {
 	markup := tgbotapi.NewReplyKeyboard(
@@ -100,3 +154,30 @@ func BuildResolveOptions(move string) tgbotapi.ReplyKeyboardMarkup {
 	markup.ResizeKeyboard = true
 	return markup
 }
+
+// BuildChoiceMenu returns keyboard with one button per choice, such as
+// residency rules or preview actions.
+func BuildChoiceMenu(choices []string) tgbotapi.ReplyKeyboardMarkup {
+	var rows [][]tgbotapi.KeyboardButton
+	for _, choice := range choices {
+		rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton(choice)))
+	}
+	rows = append(rows, tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад в меню")))
+	markup := tgbotapi.NewReplyKeyboard(rows...)
+	markup.ResizeKeyboard = true
+	return markup
+}
+
+// BuildAnswerMenu returns keyboard for yes/no questionnaire answers.
+func BuildAnswerMenu() tgbotapi.ReplyKeyboardMarkup {
+	markup := tgbotapi.NewReplyKeyboard(
+		tgbotapi.NewKeyboardButtonRow(
+			tgbotapi.NewKeyboardButton("✅ Да"),
+			tgbotapi.NewKeyboardButton("🚫 Нет"),
+		),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("⏭ Пропустить")),
+		tgbotapi.NewKeyboardButtonRow(tgbotapi.NewKeyboardButton("🔙 Назад в меню")),
+	)
+	markup.ResizeKeyboard = true
+	return markup
+}
@@ -0,0 +1,30 @@
+package model
+
+// Crossing directions.
+const (
+	DirectionEntry = "entry"
+	DirectionExit  = "exit"
+)
+
+// Crossing is one border crossing as it appears in official records.
+type Crossing struct {
+	Date string `json:"date"`
+	// Time is an optional local "15:04" time of the crossing.
+	Time string `json:"time,omitempty"`
+	// Direction is DirectionEntry or DirectionExit.
+	Direction string `json:"direction"`
+	// Country is the country entered or left.
+	Country    string `json:"country"`
+	Checkpoint string `json:"checkpoint,omitempty"`
+}
+
+// DirectionTitle returns the human readable name of the direction.
+func DirectionTitle(direction string) string {
+	switch direction {
+	case DirectionEntry:
+		return "въезд"
+	case DirectionExit:
+		return "выезд"
+	}
+	return direction
+}
@@ -3,4 +3,19 @@ package model
 type Data struct {
 	Periods []Period `json:"periods"`
 	Current string   `json:"current"`
+	Rule    string   `json:"rule,omitempty"`
+	// DayPolicy overrides how travel days are counted; empty uses the rule's.
+	DayPolicy string `json:"day_policy,omitempty"`
+	// GapStrategy decides whom days between periods are attributed to.
+	GapStrategy string `json:"gap_strategy,omitempty"`
+	// HomeCountry receives the gaps under the "home" gap strategy.
+	HomeCountry string `json:"home_country,omitempty"`
+	// SecondRule is the rule of another jurisdiction compared with Rule to
+	// find dual residence.
+	SecondRule string `json:"second_rule,omitempty"`
+	// Answers keeps questionnaire answers used by residency rules.
+	Answers map[string]string `json:"answers,omitempty"`
+	// Crossings is the border crossing log; when present, Periods are
+	// derived from it.
+	Crossings []Crossing `json:"crossings,omitempty"`
 }
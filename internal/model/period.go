@@ -1,7 +1,69 @@
 package model
 
+// Period flags that change how a period is counted by residency rules.
+const (
+	// FlagExemptIndividual marks days of an exempt individual (student,
+	// teacher, diplomat) excluded from the US Substantial Presence Test.
+	FlagExemptIndividual = "exempt_individual"
+	// FlagCloserConnection claims a closer connection to a foreign country
+	// for the US Substantial Presence Test.
+	FlagCloserConnection = "closer_connection"
+	// FlagMedicalCondition marks days in the US when a medical condition
+	// that arose there prevented leaving; they are excluded from the SPT.
+	FlagMedicalCondition = "medical_condition"
+)
+
+// Period purposes that residency rules may exempt from counting.
+const (
+	PurposeTreatment    = "treatment"
+	PurposeStudy        = "study"
+	PurposeOffshore     = "offshore"
+	PurposeForceMajeure = "force_majeure"
+)
+
+// Purposes lists the purposes with their titles in display order.
+var Purposes = []struct {
+	Purpose string
+	Title   string
+}{
+	{PurposeTreatment, "Лечение"},
+	{PurposeStudy, "Обучение"},
+	{PurposeOffshore, "Работа на шельфе"},
+	{PurposeForceMajeure, "Форс-мажор"},
+}
+
+// PurposeTitle returns the human readable name of the purpose.
+func PurposeTitle(purpose string) string {
+	for _, p := range Purposes {
+		if p.Purpose == purpose {
+			return p.Title
+		}
+	}
+	return purpose
+}
+
 type Period struct {
-	In      string `json:"in,omitempty"`
-	Out     string `json:"out,omitempty"`
-	Country string `json:"country"`
+	In      string   `json:"in,omitempty"`
+	Out     string   `json:"out,omitempty"`
+	Country string   `json:"country"`
+	Flags   []string `json:"flags,omitempty"`
+	// Purpose of the stay; see the Purpose* constants.
+	Purpose string `json:"purpose,omitempty"`
+	// Region is an optional state, province or canton within Country.
+	Region string `json:"region,omitempty"`
+	// InTime and OutTime are optional local "15:04" times of arrival and
+	// departure in TimeZone, an IANA name such as "Europe/Moscow".
+	InTime   string `json:"in_time,omitempty"`
+	OutTime  string `json:"out_time,omitempty"`
+	TimeZone string `json:"tz,omitempty"`
+}
+
+// HasFlag reports whether the period is marked with the flag.
+func (p Period) HasFlag(flag string) bool {
+	for _, f := range p.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
 }
@@ -18,6 +18,12 @@ type Session struct {
 	PendingAction string
 	TempEditedIn  string
 	TempEditedOut string
+	// Step is the position inside a multi-step questionnaire.
+	Step int
+	// TaxYear is the first day of the tax year a questionnaire is about.
+	TaxYear string
+	// TempCrossings holds imported crossings until the user confirms them.
+	TempCrossings []Crossing
 }
 
 func (s *Session) BackupSession() {
@@ -59,13 +65,30 @@ func (s *Session) BuildPeriodsList() string {
 		if out == "" {
 			out = "по " + s.Data.Current
 		}
+		if p.In != "" && p.InTime != "" {
+			in += " " + p.InTime
+		}
+		if p.Out != "" && p.OutTime != "" {
+			out += " " + p.OutTime
+		}
+		if p.TimeZone != "" {
+			out += ", " + p.TimeZone
+		}
 		flag := ""
 		if p.Country == "unknown" {
 			flag = "🕳 "
 		} else if code, ok := utils.CountryCodeMap[p.Country]; ok {
 			flag = utils.CountryToFlag(code) + " "
 		}
-		builder.WriteString(fmt.Sprintf("%d. %s%s (%s — %s)\n", i+1, flag, p.Country, in, out))
+		purpose := ""
+		if p.Purpose != "" {
+			purpose = fmt.Sprintf(" [%s]", strings.ToLower(PurposeTitle(p.Purpose)))
+		}
+		country := p.Country
+		if p.Region != "" {
+			country += ", " + utils.RegionName(p.Country, p.Region)
+		}
+		builder.WriteString(fmt.Sprintf("%d. %s%s (%s — %s)%s\n", i+1, flag, country, in, out, purpose))
 	}
 	return builder.String()
 }
@@ -0,0 +1,95 @@
+package reportbuilder
+
+import (
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+// DayPolicy decides which country a travel day counts for. A travel day is
+// a date that is both the Out of one period and the In of the next.
+type DayPolicy string
+
+const (
+	// DayBoth counts the travel day in both countries (any part of a day).
+	DayBoth DayPolicy = "both"
+	// DayArrival counts the travel day only in the arrival country.
+	DayArrival DayPolicy = "arrival"
+	// DayDeparture counts the travel day only in the departure country.
+	DayDeparture DayPolicy = "departure"
+	// DayMidnight counts the day where the person is at the end of it.
+	DayMidnight DayPolicy = "midnight"
+)
+
+// DayPolicies lists the policies with their titles in display order.
+var DayPolicies = []struct {
+	Policy DayPolicy
+	Title  string
+}{
+	{DayBoth, "Обе страны"},
+	{DayArrival, "Только страна прибытия"},
+	{DayDeparture, "Только страна выезда"},
+	{DayMidnight, "Где вы в полночь"},
+}
+
+// DayPolicyTitle returns the human readable name of the policy.
+func DayPolicyTitle(policy DayPolicy) string {
+	for _, p := range DayPolicies {
+		if p.Policy == policy {
+			return p.Title
+		}
+	}
+	return string(policy)
+}
+
+// dayPolicyRule is implemented by rules with their own day-counting policy.
+type dayPolicyRule interface {
+	DayPolicy() DayPolicy
+}
+
+// PolicyFor returns the policy set in data or, if none or unknown, the
+// rule's own one.
+func PolicyFor(data model.Data, rule ResidencyRule) DayPolicy {
+	for _, p := range DayPolicies {
+		if string(p.Policy) == data.DayPolicy {
+			return p.Policy
+		}
+	}
+	if r, ok := rule.(dayPolicyRule); ok {
+		return r.DayPolicy()
+	}
+	return DayBoth
+}
+
+// TravelDay records how a travel day was allocated.
+type TravelDay struct {
+	Date       time.Time
+	From       string
+	To         string
+	CountedFor []string
+}
+
+// travelIn returns the travel days inside [from, to].
+func travelIn(travel []TravelDay, from, to time.Time) []TravelDay {
+	var list []TravelDay
+	for _, t := range travel {
+		if !t.Date.Before(from) && !t.Date.After(to) {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// allocate applies the policy to a travel day between the previous and the
+// next span and reports which countries keep the day.
+func (p DayPolicy) allocate(prev, next *span) []string {
+	switch p {
+	case DayArrival, DayMidnight:
+		prev.To = prev.To.AddDate(0, 0, -1)
+		return []string{next.Country}
+	case DayDeparture:
+		next.From = next.From.AddDate(0, 0, 1)
+		return []string{prev.Country}
+	default:
+		return []string{prev.Country, next.Country}
+	}
+}
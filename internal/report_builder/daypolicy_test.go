@@ -0,0 +1,80 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestDayPolicies(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "10.01.2024", Country: "Грузия"},
+			{In: "10.01.2024", Out: "20.01.2024", Country: "Армения"},
+		},
+		Current: "20.01.2024",
+	}
+	calcDate, _ := utils.ParseDate(data.Current)
+
+	tests := []struct {
+		policy  DayPolicy
+		georgia int
+		armenia int
+		counted []string
+	}{
+		{DayBoth, 10, 11, []string{"Грузия", "Армения"}},
+		{DayArrival, 9, 11, []string{"Армения"}},
+		{DayDeparture, 10, 10, []string{"Грузия"}},
+		{DayMidnight, 9, 11, []string{"Армения"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			data.DayPolicy = string(tt.policy)
+			report := BuildReportFor(data, RuleByName(DefaultRule), calcDate)
+			days := map[string]int{}
+			for _, c := range report.Countries {
+				days[c.Country] = c.Days
+			}
+			if days["Грузия"] != tt.georgia || days["Армения"] != tt.armenia {
+				t.Fatalf("unexpected days %v", days)
+			}
+			if len(report.TravelDays) != 1 {
+				t.Fatalf("expected one travel day, got %d", len(report.TravelDays))
+			}
+			got := report.TravelDays[0].CountedFor
+			if len(got) != len(tt.counted) || got[0] != tt.counted[0] {
+				t.Fatalf("expected %v, got %v", tt.counted, got)
+			}
+		})
+	}
+}
+
+func TestPolicyForDefaults(t *testing.T) {
+	if got := PolicyFor(model.Data{}, RuleByName(DefaultRule)); got != DayBoth {
+		t.Fatalf("expected both for rolling rule, got %s", got)
+	}
+	if got := PolicyFor(model.Data{}, RuleByName("uk_srt")); got != DayMidnight {
+		t.Fatalf("expected midnight for UK, got %s", got)
+	}
+	if got := PolicyFor(model.Data{DayPolicy: "departure"}, RuleByName("uk_srt")); got != DayDeparture {
+		t.Fatalf("expected override, got %s", got)
+	}
+}
+
+func TestSameCountryNotDoubleCounted(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "10.01.2024", Country: "Грузия"},
+			{In: "10.01.2024", Out: "20.01.2024", Country: "Грузия"},
+		},
+		Current: "20.01.2024",
+	}
+	report := BuildReport(data)
+	if len(report.Countries) != 1 || report.Countries[0].Days != 20 {
+		t.Fatalf("expected 20 days, got %+v", report.Countries)
+	}
+	if len(report.TravelDays) != 0 {
+		t.Fatalf("expected no travel days, got %d", len(report.TravelDays))
+	}
+}
@@ -0,0 +1,167 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+const unknownCountry = "unknown"
+
+// span is a stretch of days spent in one country, both bounds inclusive.
+type span struct {
+	From    time.Time
+	To      time.Time
+	Country string
+	// Period is the source period, zero for gaps.
+	Period model.Period
+	// Index is the position of the source period in data.Periods; for a
+	// gap, of the period after it.
+	Index int
+}
+
+// resolveSpans turns periods into dated spans. An open start of the first
+// period begins at windowStart, an open end stops at calcDate. Gaps between
+// periods become "unknown" spans or are attributed by the gap strategy,
+// travel days are allocated by the policy.
+// Dates are local to each period; when departure and arrival times are
+// known, chronology is checked on instants and the midnight policy drops
+// the departure day.
+func resolveSpans(data model.Data, calcDate, windowStart time.Time, policy DayPolicy) ([]span, []TravelDay, error) {
+	var spans []span
+	var travel []TravelDay
+	var previousOutDate time.Time
+	var previousDeparture time.Time // момент выезда, если известно время
+	prev := -1                      // индекс span предыдущего периода
+
+	for i, period := range data.Periods {
+		var inDate, outDate time.Time
+		var err error
+		if period.Out != "" {
+			if outDate, err = utils.ParseDate(period.Out); err != nil {
+				return nil, nil, &ReportError{Code: ErrInvalidDate, Period: i + 1, Message: fmt.Sprintf("некорректная дата выезда «%s» (период %d)", period.Out, i+1)}
+			}
+		} else {
+			outDate = calcDate
+		}
+
+		if i == 0 && period.In == "" {
+			inDate = windowStart
+		} else if inDate, err = utils.ParseDate(period.In); err != nil {
+			return nil, nil, &ReportError{Code: ErrInvalidDate, Period: i + 1, Message: fmt.Sprintf("некорректная дата въезда «%s» (период %d)", period.In, i+1)}
+		}
+
+		arrival, departure, err := periodInstants(period)
+		if err != nil {
+			return nil, nil, &ReportError{Code: ErrInvalidDate, Period: i + 1, Message: fmt.Sprintf("некорректное время или часовой пояс (период %d): %v", i+1, err)}
+		}
+
+		// проверка хронологии: по моментам, если время известно, иначе по датам
+		if i > 0 {
+			disordered := inDate.Before(previousOutDate)
+			if !arrival.IsZero() && !previousDeparture.IsZero() {
+				disordered = arrival.Before(previousDeparture)
+			}
+			if disordered {
+				return nil, nil, &ReportError{Code: ErrChronology, Period: i + 1, Message: fmt.Sprintf("периоды не в хронологическом порядке (период %d)", i+1)}
+			}
+		}
+
+		current := span{From: inDate, To: outDate, Country: period.Country, Period: period, Index: i}
+		// в полночь дня вылета человек уже не в стране
+		if policy == DayMidnight && !departure.IsZero() {
+			current.To = current.To.AddDate(0, 0, -1)
+		}
+		departedBefore := policy == DayMidnight && !previousDeparture.IsZero()
+
+		// обработка разрыва или дня переезда между предыдущим и текущим
+		if i > 0 {
+			gapStart := previousOutDate.AddDate(0, 0, 1)
+			switch {
+			case gapStart.Before(inDate):
+				spans = append(spans, span{From: gapStart, To: inDate.AddDate(0, 0, -1), Country: unknownCountry, Index: i})
+			case prev >= 0 && inDate.Equal(previousOutDate) && spans[prev].Country == current.Country:
+				if !departedBefore {
+					spans[prev].To = spans[prev].To.AddDate(0, 0, -1)
+				}
+			case prev >= 0 && inDate.Equal(previousOutDate) && departedBefore:
+				travel = append(travel, TravelDay{Date: inDate, From: spans[prev].Country, To: current.Country, CountedFor: []string{current.Country}})
+			case prev >= 0 && inDate.Equal(previousOutDate):
+				counted := policy.allocate(&spans[prev], &current)
+				travel = append(travel, TravelDay{Date: inDate, From: spans[prev].Country, To: current.Country, CountedFor: counted})
+			}
+		}
+
+		previousOutDate = outDate
+		previousDeparture = departure
+		prev = -1
+		if inDate.After(outDate) {
+			continue
+		}
+		spans = append(spans, current)
+		prev = len(spans) - 1
+	}
+	return attributeGaps(spans, GapStrategyOf(data), data.HomeCountry), travel, nil
+}
+
+// periodInstants returns the arrival and departure instants of the period,
+// zero when the date or the time is not given.
+func periodInstants(period model.Period) (time.Time, time.Time, error) {
+	var arrival, departure time.Time
+	var err error
+	if period.In != "" && period.InTime != "" {
+		if arrival, err = utils.ParseDateTime(period.In, period.InTime, period.TimeZone); err != nil {
+			return arrival, departure, err
+		}
+	}
+	if period.Out != "" && period.OutTime != "" {
+		if departure, err = utils.ParseDateTime(period.Out, period.OutTime, period.TimeZone); err != nil {
+			return arrival, departure, err
+		}
+	}
+	return arrival, departure, nil
+}
+
+// countDays sums days per country inside [from, to].
+func countDays(spans []span, from, to time.Time) map[string]int {
+	countryDays := make(map[string]int)
+	for _, s := range spans {
+		countryDays[s.Country] += s.daysIn(from, to)
+	}
+	return countryDays
+}
+
+// daysIn returns how many days of the span fall inside [from, to].
+func (s span) daysIn(from, to time.Time) int {
+	start, end := s.From, s.To
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if start.After(end) {
+		return 0
+	}
+	return daysBetween(start, end)
+}
+
+// daysBetween returns the number of days in [from, to].
+func daysBetween(from, to time.Time) int {
+	return utils.DayNumber(to) - utils.DayNumber(from) + 1
+}
+
+// leader returns the known country with the most days.
+func leader(countryDays map[string]int) (string, int) {
+	country, days := "", 0
+	for c, d := range countryDays {
+		if c == unknownCountry {
+			continue
+		}
+		if d > days || (d == days && c < country) {
+			country, days = c, d
+		}
+	}
+	return country, days
+}
@@ -0,0 +1,94 @@
+package reportbuilder
+
+import (
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+// ukExceptionalLimit caps exceptional-circumstances days per UK tax year.
+const ukExceptionalLimit = 60
+
+// ExcludedDays are days of a period a rule did not count in its country.
+type ExcludedDays struct {
+	From    time.Time
+	To      time.Time
+	Country string
+	Purpose string
+	Days    int
+	// CountedAs is the country credited with the days instead, empty when
+	// the days are not counted at all.
+	CountedAs string
+}
+
+// russiaCountry is the only country art. 207 credits days to.
+const russiaCountry = "Россия"
+
+// creditHome applies RU TC art. 207: departures from Russia for treatment or
+// study shorter than six months and offshore work do not interrupt the stay
+// there, so their days are credited to Russia. Trips between other countries
+// are counted as they are.
+func creditHome(spans []span, from, to time.Time) ([]span, []ExcludedDays) {
+	result := make([]span, len(spans))
+	copy(result, spans)
+
+	var excluded []ExcludedDays
+	home := ""
+	for i, s := range result {
+		if s.Country == unknownCountry {
+			home = ""
+			continue
+		}
+		if home != russiaCountry || home == s.Country || !creditsHome(s) {
+			home = s.Country
+			continue
+		}
+		if days := s.daysIn(from, to); days > 0 {
+			excluded = append(excluded, ExcludedDays{From: s.From, To: s.To, Country: s.Country, Purpose: s.Period.Purpose, Days: days, CountedAs: home})
+		}
+		result[i].Country = home
+	}
+
+	// день переезда мог оказаться засчитан одной стране дважды
+	last := make(map[string]time.Time)
+	for i, s := range result {
+		if end, ok := last[s.Country]; ok && !s.From.After(end) {
+			result[i].From = end.AddDate(0, 0, 1)
+		}
+		if end, ok := last[s.Country]; !ok || s.To.After(end) {
+			last[s.Country] = s.To
+		}
+	}
+	return result, excluded
+}
+
+func creditsHome(s span) bool {
+	switch s.Period.Purpose {
+	case model.PurposeOffshore:
+		return true
+	case model.PurposeTreatment, model.PurposeStudy:
+		return s.To.Before(s.From.AddDate(0, 6, 0))
+	}
+	return false
+}
+
+// excludeCapped drops up to limit days in country spent for purpose within
+// [from, to] and returns the number of dropped days.
+func excludeCapped(spans []span, country, purpose string, limit int, from, to time.Time) (int, []ExcludedDays) {
+	total := 0
+	var excluded []ExcludedDays
+	for _, s := range spans {
+		if s.Country != country || s.Period.Purpose != purpose {
+			continue
+		}
+		days := s.daysIn(from, to)
+		if days > limit-total {
+			days = limit - total
+		}
+		if days <= 0 {
+			continue
+		}
+		total += days
+		excluded = append(excluded, ExcludedDays{From: s.From, To: s.To, Country: s.Country, Purpose: purpose, Days: days})
+	}
+	return total, excluded
+}
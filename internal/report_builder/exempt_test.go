@@ -0,0 +1,118 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestRollingCreditsTreatmentToHome(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "01.03.2024", Country: "Россия"},
+			{In: "01.03.2024", Out: "30.04.2024", Country: "Израиль", Purpose: model.PurposeTreatment},
+			{In: "30.04.2024", Out: "30.06.2024", Country: "Россия"},
+		},
+		Current: "30.06.2024",
+	}
+	report := BuildReport(data)
+	if len(report.Countries) != 1 || report.Countries[0].Country != "Россия" {
+		t.Fatalf("expected only Россия, got %+v", report.Countries)
+	}
+	if report.Countries[0].Days != 182 {
+		t.Fatalf("expected 182 days, got %d", report.Countries[0].Days)
+	}
+	if len(report.Excluded) != 1 || report.Excluded[0].CountedAs != "Россия" || report.Excluded[0].Days != 61 {
+		t.Fatalf("unexpected excluded days %+v", report.Excluded)
+	}
+}
+
+func TestRollingLongStudyNotCredited(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2023", Out: "31.12.2023", Country: "Россия"},
+			{In: "01.01.2024", Out: "30.09.2024", Country: "Германия", Purpose: model.PurposeStudy},
+		},
+		Current: "30.09.2024",
+	}
+	report := BuildReport(data)
+	if len(report.Excluded) != 0 {
+		t.Fatalf("expected no exclusions, got %+v", report.Excluded)
+	}
+	if report.Country != "Германия" {
+		t.Fatalf("expected Германия, got %s", report.Country)
+	}
+}
+
+func TestRollingCreditsOnlyDeparturesFromRussia(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "01.03.2024", Country: "Германия"},
+			{In: "01.03.2024", Out: "30.04.2024", Country: "Франция", Purpose: model.PurposeStudy},
+			{In: "30.04.2024", Out: "30.06.2024", Country: "Германия"},
+		},
+		Current: "30.06.2024",
+	}
+	report := BuildReport(data)
+	if len(report.Excluded) != 0 {
+		t.Fatalf("expected no exclusions, got %+v", report.Excluded)
+	}
+	if len(report.Countries) != 2 {
+		t.Fatalf("expected Германия and Франция, got %+v", report.Countries)
+	}
+}
+
+func TestUKExceptionalDaysCapped(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "06.04.2024", Out: "05.06.2024", Country: ukCountry},
+			{In: "06.06.2024", Out: "19.08.2024", Country: ukCountry, Purpose: model.PurposeForceMajeure},
+		},
+		Current: "05.04.2025",
+	}
+	calcDate, _ := utils.ParseDate(data.Current)
+	result, err := RuleByName("uk_srt").Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 61 + 75 дней, из них 60 не учитываются
+	if result.Days != 76 {
+		t.Fatalf("expected 76 days, got %d", result.Days)
+	}
+	if len(result.Excluded) != 1 || result.Excluded[0].Days != ukExceptionalLimit {
+		t.Fatalf("unexpected excluded days %+v", result.Excluded)
+	}
+}
+
+func TestSPTMedicalCondition(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "31.05.2024", Country: usCountry},
+			{In: "01.06.2024", Out: "31.07.2024", Country: usCountry, Flags: []string{model.FlagMedicalCondition}},
+		},
+		Current: "31.12.2024",
+	}
+	calcDate, _ := utils.ParseDate(data.Current)
+	result, err := RuleByName("us_spt").Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Days != 152 || result.Verdict != VerdictNonResident {
+		t.Fatalf("expected 152 days and non resident, got %d %s", result.Days, result.Verdict)
+	}
+	if len(result.Excluded) != 1 || result.Excluded[0].Days != 61 {
+		t.Fatalf("unexpected excluded days %+v", result.Excluded)
+	}
+
+	// поездка на лечение или учёбу сама по себе дни не исключает
+	data.Periods[1].Flags = nil
+	data.Periods[1].Purpose = model.PurposeTreatment
+	result, err = RuleByName("us_spt").Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Days != 213 || len(result.Excluded) != 0 {
+		t.Fatalf("expected 213 counted days, got %d, excluded %+v", result.Days, result.Excluded)
+	}
+}
@@ -0,0 +1,141 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// Forecast answers planning questions for one country under the rolling
+// 12-month rule. Periods are known up to Start; later days are hypothetical.
+type Forecast struct {
+	Country   string
+	Start     time.Time
+	Target    time.Time
+	Threshold int
+	// DaysNow is the number of days in the window ending on Start.
+	DaysNow int
+	// DaysAtTarget counts the known days that remain in the window ending
+	// on Target.
+	DaysAtTarget int
+	// Allowance is how many more days may be spent in the country between
+	// Start and Target, in any order, without reaching the threshold on any
+	// day up to Target; known days rolling out of the window free room only
+	// once they are out.
+	Allowance int
+	// ResidentFrom is the date residency starts when staying in the country
+	// continuously from the day after Start.
+	ResidentFrom time.Time
+	// KeepsUntil is the last date residency holds when leaving right after
+	// Start; zero if not resident on Start.
+	KeepsUntil time.Time
+	// StayUntil is the earliest date one may leave, staying continuously
+	// from the day after Start, and still reach the threshold on Target;
+	// zero when the known days already suffice or it is impossible.
+	StayUntil time.Time
+}
+
+// BuildForecast plans days in country from the calculation date to target.
+func BuildForecast(data model.Data, country string, target time.Time, threshold int) (Forecast, error) {
+	start, err := utils.ParseDate(data.Current)
+	if err != nil {
+		return Forecast{}, &ReportError{Code: ErrInvalidDate, Message: fmt.Sprintf("некорректная дата расчёта «%s»", data.Current)}
+	}
+	if !target.After(start) {
+		return Forecast{}, fmt.Errorf("дата планирования должна быть позже даты расчёта %s", utils.FormatDate(start))
+	}
+
+	ledgerStart := start.AddDate(-1, 0, 1)
+	spans, _, err := resolveSpans(data, start, ledgerStart, PolicyFor(data, RuleByName(DefaultRule)))
+	if err != nil {
+		return Forecast{}, err
+	}
+	ledger := newLedger(spans, ledgerStart, start)
+
+	// known — дни из периодов, extra — гипотетическое пребывание с start+1 по stayTo
+	count := func(day, stayTo time.Time) int {
+		windowStart := day.AddDate(-1, 0, 1)
+		days := ledger.Count(country, windowStart, day)
+		from := start.AddDate(0, 0, 1)
+		if from.Before(windowStart) {
+			from = windowStart
+		}
+		if stayTo.After(day) {
+			stayTo = day
+		}
+		if !from.After(stayTo) {
+			days += daysBetween(from, stayTo)
+		}
+		return days
+	}
+
+	f := Forecast{
+		Country:      country,
+		Start:        start,
+		Target:       target,
+		Threshold:    threshold,
+		DaysNow:      count(start, start),
+		DaysAtTarget: count(target, start),
+	}
+	// каждое окно до target должно остаться ниже порога, даже если все новые
+	// дни придутся на него
+	f.Allowance = daysBetween(start.AddDate(0, 0, 1), target)
+	for day := start.AddDate(0, 0, 1); !day.After(target); day = day.AddDate(0, 0, 1) {
+		f.Allowance = min(f.Allowance, threshold-1-count(day, start))
+	}
+	f.Allowance = max(f.Allowance, 0)
+
+	for day := start; day.Before(start.AddDate(1, 0, 1)); day = day.AddDate(0, 0, 1) {
+		if count(day, day) >= threshold {
+			f.ResidentFrom = day
+			break
+		}
+	}
+	if f.DaysNow >= threshold {
+		for day := start; count(day, start) >= threshold; day = day.AddDate(0, 0, 1) {
+			f.KeepsUntil = day
+		}
+	}
+	if f.DaysAtTarget < threshold {
+		for leave := start.AddDate(0, 0, 1); !leave.After(target); leave = leave.AddDate(0, 0, 1) {
+			if count(target, leave) >= threshold {
+				f.StayUntil = leave
+				break
+			}
+		}
+	}
+	return f, nil
+}
+
+// RenderForecast formats the forecast as a Telegram message.
+func RenderForecast(f Forecast) string {
+	flag := utils.CountryToFlag(utils.CountryCodeMap[f.Country])
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("🧮 План: %s %s на %s\n\n", flag, f.Country, utils.FormatDate(f.Target)))
+	builder.WriteString(fmt.Sprintf("На %s: %d дней за 12 месяцев\n", utils.FormatDate(f.Start), f.DaysNow))
+	builder.WriteString(fmt.Sprintf("Из них останется в окне на %s: %d дней\n\n", utils.FormatDate(f.Target), f.DaysAtTarget))
+
+	if f.DaysAtTarget >= f.Threshold {
+		builder.WriteString(fmt.Sprintf("✅ Порог %d дней на %s уже набран, даже если больше не приезжать.\n", f.Threshold, utils.FormatDate(f.Target)))
+	} else {
+		if f.Allowance > 0 {
+			builder.WriteString(fmt.Sprintf("➖ Чтобы ни в один день до %s не стать резидентом, можно провести в стране ещё %d дней.\n", utils.FormatDate(f.Target), f.Allowance))
+		} else {
+			builder.WriteString(fmt.Sprintf("➖ Запаса нет: с уже известными днями любой новый день в стране до %s может сделать вас резидентом.\n", utils.FormatDate(f.Target)))
+		}
+		if !f.StayUntil.IsZero() {
+			builder.WriteString(fmt.Sprintf("➕ Чтобы быть резидентом на %s, оставайтесь в стране без перерыва как минимум до %s.\n", utils.FormatDate(f.Target), utils.FormatDate(f.StayUntil)))
+		} else {
+			builder.WriteString(fmt.Sprintf("➕ Набрать %d дней к %s уже невозможно.\n", f.Threshold, utils.FormatDate(f.Target)))
+		}
+	}
+	if !f.ResidentFrom.IsZero() && f.DaysNow < f.Threshold {
+		builder.WriteString(fmt.Sprintf("📍 При непрерывном пребывании резидентство наступит %s.\n", utils.FormatDate(f.ResidentFrom)))
+	}
+	if !f.KeepsUntil.IsZero() {
+		builder.WriteString(fmt.Sprintf("📤 Если уехать сейчас, резидентство сохранится до %s включительно — старые дни выпадают из окна.\n", utils.FormatDate(f.KeepsUntil)))
+	}
+	return builder.String()
+}
@@ -0,0 +1,75 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestBuildForecast(t *testing.T) {
+	data := model.Data{
+		Current: "30.06.2024",
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "31.03.2024", Country: "Грузия"},
+			{In: "01.04.2024", Out: "30.06.2024", Country: "Армения"},
+		},
+	}
+	target, _ := utils.ParseDate("31.12.2024")
+	f, err := BuildForecast(data, "Грузия", target, 183)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.DaysNow != 91 || f.DaysAtTarget != 91 || f.Allowance != 91 {
+		t.Fatalf("unexpected counts: %+v", f)
+	}
+	// 92 дня с 01.07 по 30.09 дают 183 дня на 31.12
+	if got := utils.FormatDate(f.StayUntil); got != "30.09.2024" {
+		t.Fatalf("expected to stay until 30.09.2024, got %s", got)
+	}
+	if got := utils.FormatDate(f.ResidentFrom); got != "30.09.2024" {
+		t.Fatalf("expected residency from 30.09.2024, got %s", got)
+	}
+	if !f.KeepsUntil.IsZero() {
+		t.Fatalf("not resident now, got keeps until %s", utils.FormatDate(f.KeepsUntil))
+	}
+}
+
+func TestBuildForecastKeepsUntil(t *testing.T) {
+	data := model.Data{
+		Current: "31.12.2024",
+		Periods: []model.Period{{In: "01.01.2024", Out: "31.12.2024", Country: "Грузия"}},
+	}
+	target, _ := utils.ParseDate("31.12.2025")
+	f, _ := BuildForecast(data, "Грузия", target, 183)
+	// окно 12 месяцев на 02.07.2025 начинается 03.07.2024: остаётся 182 дня
+	if got := utils.FormatDate(f.KeepsUntil); got != "01.07.2025" {
+		t.Fatalf("expected to keep residency until 01.07.2025, got %s", got)
+	}
+}
+
+func TestBuildForecastAllowanceEveryWindow(t *testing.T) {
+	target, _ := utils.ParseDate("31.12.2025")
+	tests := []struct {
+		in   string
+		want int
+	}{
+		// 184 дня: резидент уже сейчас, запаса нет
+		{"01.07.2024", 0},
+		// 180 дней: на следующий день окно ещё полное, запас — 2 дня
+		{"05.07.2024", 2},
+	}
+	for _, tt := range tests {
+		data := model.Data{
+			Current: "31.12.2024",
+			Periods: []model.Period{{In: tt.in, Out: "31.12.2024", Country: "Грузия"}},
+		}
+		f, err := BuildForecast(data, "Грузия", target, 183)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.Allowance != tt.want {
+			t.Errorf("from %s: expected allowance %d, got %d", tt.in, tt.want, f.Allowance)
+		}
+	}
+}
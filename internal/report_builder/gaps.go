@@ -0,0 +1,220 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// GapStrategy decides whom the days between periods are attributed to.
+type GapStrategy string
+
+const (
+	// GapUnknown keeps gaps as "unknown" days that may flip the verdict.
+	GapUnknown GapStrategy = "unknown"
+	// GapPrevious attributes a gap to the country before it.
+	GapPrevious GapStrategy = "previous"
+	// GapNext attributes a gap to the country after it.
+	GapNext GapStrategy = "next"
+	// GapHome attributes gaps to model.Data.HomeCountry.
+	GapHome GapStrategy = "home"
+	// GapExclude drops gaps from the calculation altogether.
+	GapExclude GapStrategy = "exclude"
+)
+
+// GapStrategies lists the strategies with their titles in display order.
+var GapStrategies = []struct {
+	Strategy GapStrategy
+	Title    string
+}{
+	{GapUnknown, "Оставить неизвестными"},
+	{GapPrevious, "Предыдущей стране"},
+	{GapNext, "Следующей стране"},
+	{GapHome, "Домашней стране"},
+	{GapExclude, "Не учитывать"},
+}
+
+// GapStrategyTitle returns the human readable name of the strategy.
+func GapStrategyTitle(strategy GapStrategy) string {
+	for _, g := range GapStrategies {
+		if g.Strategy == strategy {
+			return g.Title
+		}
+	}
+	return string(strategy)
+}
+
+// GapStrategyOf returns the strategy set in data; unknown values and the
+// home strategy without a home country fall back to GapUnknown.
+func GapStrategyOf(data model.Data) GapStrategy {
+	strategy := GapStrategy(data.GapStrategy)
+	switch strategy {
+	case GapPrevious, GapNext, GapExclude:
+		return strategy
+	case GapHome:
+		if data.HomeCountry != "" {
+			return strategy
+		}
+	}
+	return GapUnknown
+}
+
+// GapFlip is another gap strategy under which the verdict differs.
+type GapFlip struct {
+	Strategy GapStrategy
+	Verdict  Verdict
+	Country  string
+}
+
+// attributeGaps applies the strategy to the unknown spans.
+func attributeGaps(spans []span, strategy GapStrategy, home string) []span {
+	if strategy == GapUnknown {
+		return spans
+	}
+	result := make([]span, 0, len(spans))
+	for i, s := range spans {
+		if s.Country != unknownCountry {
+			result = append(result, s)
+			continue
+		}
+		switch {
+		case strategy == GapPrevious && i > 0:
+			s.Country = spans[i-1].Country
+		case strategy == GapNext && i+1 < len(spans):
+			s.Country = spans[i+1].Country
+		case strategy == GapHome:
+			s.Country = home
+		case strategy == GapExclude:
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// gapFlips evaluates the rule under the other gap strategies and returns
+// those that change the verdict or the country.
+func gapFlips(data model.Data, rule ResidencyRule, calcDate time.Time, result RuleResult) []GapFlip {
+	chosen := GapStrategyOf(data)
+	var flips []GapFlip
+	for _, g := range GapStrategies {
+		if g.Strategy == chosen || (g.Strategy == GapHome && data.HomeCountry == "") {
+			continue
+		}
+		other := data
+		other.GapStrategy = string(g.Strategy)
+		alt, err := rule.Evaluate(other, calcDate)
+		if err != nil {
+			continue
+		}
+		if alt.Verdict != result.Verdict || (alt.Verdict == VerdictResident && alt.Country != result.Country) {
+			flips = append(flips, GapFlip{Strategy: g.Strategy, Verdict: alt.Verdict, Country: alt.Country})
+		}
+	}
+	return flips
+}
+
+// Gap is a stretch of unknown days: between two periods or an explicit
+// "unknown" period.
+type Gap struct {
+	From time.Time
+	To   time.Time
+	// Before and After are the neighbouring known countries, if any.
+	Before string
+	After  string
+	// Period is the index of the "unknown" period, -1 for a gap between
+	// periods; Insert is the index a period filling the gap is placed at.
+	Period int
+	Insert int
+}
+
+// Days returns the length of the gap.
+func (g Gap) Days() int {
+	return daysBetween(g.From, g.To)
+}
+
+// FindGaps lists the unknown days of the report window of data's rule, as
+// the report counts them, in date order.
+func FindGaps(data model.Data) ([]Gap, error) {
+	calcDate, err := utils.ParseDate(data.Current)
+	if err != nil {
+		return nil, &ReportError{Code: ErrInvalidDate, Message: fmt.Sprintf("некорректная дата расчёта «%s»", data.Current)}
+	}
+	rule := RuleByName(data.Rule)
+	result, err := rule.Evaluate(data, calcDate)
+	if err != nil {
+		return nil, err
+	}
+	data.GapStrategy = string(GapUnknown)
+	spans, _, err := resolveSpans(data, calcDate, result.From, PolicyFor(data, rule))
+	if err != nil {
+		return nil, err
+	}
+
+	known := func(i int) string {
+		if i < 0 || i >= len(spans) || spans[i].Country == unknownCountry {
+			return ""
+		}
+		return spans[i].Country
+	}
+	var gaps []Gap
+	for i, s := range spans {
+		if s.Country != unknownCountry {
+			continue
+		}
+		gap := Gap{From: s.From, To: s.To, Before: known(i - 1), After: known(i + 1), Period: -1, Insert: s.Index}
+		if s.Period.Country != "" {
+			// весь период «unknown», без поправок на день переезда
+			gap.Period = s.Index
+			if out, err := utils.ParseDate(s.Period.Out); err == nil {
+				gap.To = out
+			}
+		}
+		if gap.From.Before(result.From) {
+			gap.From = result.From
+		}
+		if gap.To.After(result.To) {
+			gap.To = result.To
+		}
+		if gap.From.After(gap.To) {
+			continue
+		}
+		gaps = append(gaps, gap)
+	}
+	return gaps, nil
+}
+
+// FillGap returns data with the gap attributed to the country. Days of an
+// "unknown" period outside the gap stay unknown.
+func FillGap(data model.Data, gap Gap, country string) model.Data {
+	filled := model.Period{In: utils.FormatDate(gap.From), Out: utils.FormatDate(gap.To), Country: country}
+	periods := make([]model.Period, 0, len(data.Periods)+2)
+	if gap.Period < 0 {
+		periods = append(periods, data.Periods[:gap.Insert]...)
+		periods = append(periods, filled)
+		data.Periods = append(periods, data.Periods[gap.Insert:]...)
+		return data
+	}
+
+	periods = append(periods, data.Periods[:gap.Period]...)
+	unknown := data.Periods[gap.Period]
+	if in, err := utils.ParseDate(unknown.In); err == nil && in.Before(gap.From) {
+		head := unknown
+		head.Out, head.OutTime = utils.FormatDate(gap.From.AddDate(0, 0, -1)), ""
+		periods = append(periods, head)
+		unknown.In, unknown.InTime = filled.In, ""
+	}
+	var tail []model.Period
+	if out, err := utils.ParseDate(unknown.Out); err == nil && out.After(gap.To) {
+		rest := unknown
+		rest.In, rest.InTime = utils.FormatDate(gap.To.AddDate(0, 0, 1)), ""
+		tail = append(tail, rest)
+		unknown.Out, unknown.OutTime = filled.Out, ""
+	}
+	unknown.Country = country
+	periods = append(periods, unknown)
+	periods = append(periods, tail...)
+	data.Periods = append(periods, data.Periods[gap.Period+1:]...)
+	return data
+}
@@ -0,0 +1,136 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func gapData(strategy GapStrategy) model.Data {
+	return model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "31.05.2024", Country: "Россия"},
+			{In: "01.08.2024", Out: "31.12.2024", Country: "Грузия"},
+		},
+		Current:     "31.12.2024",
+		GapStrategy: string(strategy),
+		HomeCountry: "Армения",
+	}
+}
+
+func TestGapStrategies(t *testing.T) {
+	tests := []struct {
+		strategy GapStrategy
+		verdict  Verdict
+		country  string
+		unknown  int
+	}{
+		{GapUnknown, VerdictInconclusive, "Грузия", 61},
+		{GapPrevious, VerdictResident, "Россия", 0},
+		{GapNext, VerdictResident, "Грузия", 0},
+		{GapHome, VerdictNonResident, "Грузия", 0},
+		{GapExclude, VerdictNonResident, "Грузия", 0},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			report := BuildReport(gapData(tt.strategy))
+			if report.Verdict != tt.verdict || report.Country != tt.country || report.UnknownDays != tt.unknown {
+				t.Fatalf("got %s %s unknown %d", report.Verdict, report.Country, report.UnknownDays)
+			}
+			if report.GapStrategy != tt.strategy {
+				t.Fatalf("expected strategy %s, got %s", tt.strategy, report.GapStrategy)
+			}
+			if len(report.GapFlips) == 0 {
+				t.Fatal("expected a warning about other strategies")
+			}
+		})
+	}
+}
+
+func TestGapFlipsListOnlyChangedVerdicts(t *testing.T) {
+	report := BuildReport(gapData(GapExclude))
+	for _, f := range report.GapFlips {
+		if f.Strategy == GapHome {
+			t.Fatalf("home strategy gives the same verdict and must not be listed")
+		}
+	}
+	if len(report.GapFlips) != 3 {
+		t.Fatalf("expected 3 flips, got %+v", report.GapFlips)
+	}
+}
+
+func TestGapHomeWithoutCountry(t *testing.T) {
+	data := gapData(GapHome)
+	data.HomeCountry = ""
+	if got := GapStrategyOf(data); got != GapUnknown {
+		t.Fatalf("expected fallback to unknown, got %s", got)
+	}
+}
+
+func TestFindAndFillGaps(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "31.01.2024", Country: "Россия"},
+			{In: "10.02.2024", Out: "29.02.2024", Country: "Грузия"},
+			{In: "01.03.2024", Out: "10.03.2024", Country: unknownCountry},
+			{In: "11.03.2024", Out: "31.03.2024", Country: "Армения"},
+		},
+		Current: "31.03.2024",
+	}
+	gaps, err := FindGaps(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 2 {
+		t.Fatalf("expected 2 gaps, got %+v", gaps)
+	}
+	if gaps[0].Days() != 9 || gaps[0].Before != "Россия" || gaps[0].After != "Грузия" || gaps[0].Period != -1 {
+		t.Fatalf("unexpected first gap %+v", gaps[0])
+	}
+	if gaps[1].Period != 2 || gaps[1].Before != "Грузия" || gaps[1].After != "Армения" {
+		t.Fatalf("unexpected second gap %+v", gaps[1])
+	}
+
+	data = FillGap(data, gaps[0], "Турция")
+	if len(data.Periods) != 5 || data.Periods[1].Country != "Турция" || data.Periods[1].In != "01.02.2024" || data.Periods[1].Out != "09.02.2024" {
+		t.Fatalf("unexpected periods after insert %+v", data.Periods)
+	}
+	gaps, _ = FindGaps(data)
+	data = FillGap(data, gaps[0], "Грузия")
+	if gaps, _ = FindGaps(data); len(gaps) != 0 || data.Periods[3].Country != "Грузия" {
+		t.Fatalf("expected no gaps, got %+v", data.Periods)
+	}
+	if report := BuildReport(data); report.UnknownDays != 0 {
+		t.Fatalf("expected no unknown days, got %d", report.UnknownDays)
+	}
+}
+
+func TestFindGapsReportWindow(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "31.01.2024", Country: "Россия"},
+			{In: "01.03.2024", Out: "30.11.2024", Country: "Грузия"},
+			{In: "01.12.2024", Out: "31.01.2025", Country: unknownCountry},
+			{In: "01.02.2025", Out: "31.12.2025", Country: "Армения"},
+		},
+		Current: "31.12.2025",
+	}
+	// февраль 2024 года вне окна 01.01.2025–31.12.2025, от периода «unknown» в окне только январь
+	gaps, err := FindGaps(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 1 || gaps[0].Period != 2 || utils.FormatDate(gaps[0].From) != "01.01.2025" || gaps[0].Days() != 31 {
+		t.Fatalf("unexpected gaps %+v", gaps)
+	}
+
+	data = FillGap(data, gaps[0], "Армения")
+	if len(data.Periods) != 5 || data.Periods[2].Country != unknownCountry || data.Periods[2].Out != "31.12.2024" ||
+		data.Periods[3].Country != "Армения" || data.Periods[3].In != "01.01.2025" || data.Periods[3].Out != "31.01.2025" {
+		t.Fatalf("unexpected periods after fill %+v", data.Periods)
+	}
+	if report := BuildReport(data); report.UnknownDays != 0 {
+		t.Fatalf("expected no unknown days, got %d", report.UnknownDays)
+	}
+}
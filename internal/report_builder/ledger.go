@@ -0,0 +1,95 @@
+package reportbuilder
+
+import (
+	"sort"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// Ledger keeps per-country prefix sums of days, so the number of days in
+// any window is answered in constant time.
+type Ledger struct {
+	Start time.Time
+	End   time.Time
+	// prefix[c][i] is the number of days in c among the first i days.
+	prefix map[string][]int
+}
+
+// newLedger records the spans between start and end inclusive.
+func newLedger(spans []span, start, end time.Time) *Ledger {
+	size := dayIndex(start, end) + 1
+	if size < 0 {
+		size = 0
+	}
+	daily := make(map[string][]int)
+	for _, s := range spans {
+		from, to := s.From, s.To
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+		if from.After(to) {
+			continue
+		}
+		if daily[s.Country] == nil {
+			daily[s.Country] = make([]int, size)
+		}
+		for i := dayIndex(start, from); i <= dayIndex(start, to); i++ {
+			daily[s.Country][i] = 1
+		}
+	}
+
+	l := &Ledger{Start: start, End: end, prefix: make(map[string][]int)}
+	for c, days := range daily {
+		prefix := make([]int, size+1)
+		for i, d := range days {
+			prefix[i+1] = prefix[i] + d
+		}
+		l.prefix[c] = prefix
+	}
+	return l
+}
+
+// Count returns the days spent in country within [from, to].
+func (l *Ledger) Count(country string, from, to time.Time) int {
+	prefix, ok := l.prefix[country]
+	if !ok {
+		return 0
+	}
+	i, j := l.clamp(from), l.clamp(to.AddDate(0, 0, 1))
+	if i >= j {
+		return 0
+	}
+	return prefix[j] - prefix[i]
+}
+
+// Countries lists the countries present in the ledger, unknown excluded.
+func (l *Ledger) Countries() []string {
+	var list []string
+	for c := range l.prefix {
+		if c != unknownCountry {
+			list = append(list, c)
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// clamp converts a date to a prefix index inside the ledger.
+func (l *Ledger) clamp(t time.Time) int {
+	i := dayIndex(l.Start, t)
+	if i < 0 {
+		return 0
+	}
+	if size := dayIndex(l.Start, l.End) + 1; i > size {
+		return size
+	}
+	return i
+}
+
+// dayIndex returns the number of days from start to t.
+func dayIndex(start, t time.Time) int {
+	return utils.DayNumber(t) - utils.DayNumber(start)
+}
@@ -0,0 +1,25 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestLedgerCount(t *testing.T) {
+	start, _ := utils.ParseDate("01.01.2024")
+	end, _ := utils.ParseDate("31.01.2024")
+	from, _ := utils.ParseDate("05.01.2024")
+	to, _ := utils.ParseDate("14.01.2024")
+	ledger := newLedger([]span{{From: from, To: to, Country: "Грузия"}}, start, end)
+
+	if got := ledger.Count("Грузия", start, end); got != 10 {
+		t.Fatalf("expected 10 days, got %d", got)
+	}
+	if got := ledger.Count("Грузия", to, end.AddDate(1, 0, 0)); got != 1 {
+		t.Fatalf("expected 1 day, got %d", got)
+	}
+	if got := ledger.Count("Армения", start, end); got != 0 {
+		t.Fatalf("expected 0 days, got %d", got)
+	}
+}
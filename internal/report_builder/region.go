@@ -0,0 +1,91 @@
+package reportbuilder
+
+import (
+	"sort"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// regionSeparator joins a country and a region into one span key.
+const regionSeparator = "/"
+
+// RegionDays is the number of days spent in a region of a country. An
+// empty Region collects days of periods without a region.
+type RegionDays struct {
+	Country string
+	Region  string
+	Days    int
+}
+
+func regionKey(country, region string) string {
+	return country + regionSeparator + utils.RegionName(country, region)
+}
+
+// splitRegion splits a span key into the country and the region.
+func splitRegion(key string) (string, string) {
+	country, region, _ := strings.Cut(key, regionSeparator)
+	return country, region
+}
+
+// placeTitle formats a country or a region key for messages.
+func placeTitle(key string) string {
+	country, region := splitRegion(key)
+	if region == "" {
+		return country
+	}
+	return country + ", " + region
+}
+
+// regionSpans resolves spans treating every region as a jurisdiction of
+// its own. State rules count any part of a day, so travel days count for
+// both regions.
+func regionSpans(data model.Data, calcDate, windowStart time.Time) ([]span, error) {
+	regional := data
+	regional.Periods = make([]model.Period, len(data.Periods))
+	for i, p := range data.Periods {
+		if p.Region != "" {
+			p.Country = regionKey(p.Country, p.Region)
+		}
+		regional.Periods[i] = p
+	}
+	spans, _, err := resolveSpans(regional, calcDate, windowStart, DayBoth)
+	return spans, err
+}
+
+// countRegions breaks days in [from, to] down by region for countries with
+// at least one regional period.
+func countRegions(data model.Data, calcDate, from, to time.Time) ([]RegionDays, error) {
+	spans, err := regionSpans(data, calcDate, from)
+	if err != nil {
+		return nil, err
+	}
+	days := countDays(spans, from, to)
+
+	regional := make(map[string]bool)
+	for key := range days {
+		if country, region := splitRegion(key); region != "" {
+			regional[country] = true
+		}
+	}
+
+	var list []RegionDays
+	for key, d := range days {
+		country, region := splitRegion(key)
+		if d == 0 || !regional[country] {
+			continue
+		}
+		list = append(list, RegionDays{Country: country, Region: region, Days: d})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Country != list[j].Country {
+			return list[i].Country < list[j].Country
+		}
+		if list[i].Days != list[j].Days {
+			return list[i].Days > list[j].Days
+		}
+		return list[i].Region < list[j].Region
+	})
+	return list, nil
+}
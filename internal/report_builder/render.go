@@ -0,0 +1,251 @@
+package reportbuilder
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+// RenderText formats the report as a Telegram message.
+func RenderText(r Report) string {
+	if r.HasErrors() {
+		builder := strings.Builder{}
+		for _, e := range r.Errors {
+			builder.WriteString(fmt.Sprintf("Ошибка: %s\n", e.Message))
+		}
+		return builder.String()
+	}
+	if r.IsEmpty() {
+		return "Нет данных для анализа за указанный период."
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("Анализ за период: %s — %s\n\n", utils.FormatDate(r.From), utils.FormatDate(r.To)))
+	for _, c := range r.Countries {
+		flag := utils.CountryToFlag(utils.CountryCodeMap[c.Country])
+		builder.WriteString(fmt.Sprintf("%s %s: %d дней\n", flag, c.Country, c.Days))
+		for _, rd := range r.Regions {
+			if rd.Country != c.Country {
+				continue
+			}
+			region := rd.Region
+			if region == "" {
+				region = "регион не указан"
+			}
+			builder.WriteString(fmt.Sprintf("   • %s: %d дней\n", region, rd.Days))
+		}
+	}
+	if r.UnknownDays > 0 {
+		builder.WriteString(fmt.Sprintf("🕳 Неизвестно где: %d дней\n", r.UnknownDays))
+	}
+	if r.GapStrategy != "" && r.GapStrategy != GapUnknown {
+		builder.WriteString(fmt.Sprintf("🕳 Пробелы между периодами: %s\n", strings.ToLower(GapStrategyTitle(r.GapStrategy))))
+	}
+	if len(r.Excluded) > 0 {
+		builder.WriteString("\n🩺 Особые основания:\n")
+		for _, e := range r.Excluded {
+			flag := utils.CountryToFlag(utils.CountryCodeMap[e.Country])
+			purpose := ""
+			if e.Purpose != "" {
+				purpose = fmt.Sprintf(" (%s)", strings.ToLower(model.PurposeTitle(e.Purpose)))
+			}
+			counted := "не учтены"
+			if e.CountedAs != "" {
+				counted = fmt.Sprintf("засчитаны: %s %s", utils.CountryToFlag(utils.CountryCodeMap[e.CountedAs]), e.CountedAs)
+			}
+			builder.WriteString(fmt.Sprintf("%s — %s %s %s%s: %d дней %s\n", utils.FormatDate(e.From), utils.FormatDate(e.To), flag, e.Country, purpose, e.Days, counted))
+		}
+	}
+	if len(r.TravelDays) > 0 {
+		builder.WriteString(fmt.Sprintf("\n✈️ Дни переезда (%s):\n", strings.ToLower(DayPolicyTitle(r.DayPolicy))))
+		for _, t := range r.TravelDays {
+			builder.WriteString(fmt.Sprintf("%s %s → %s: засчитан %s\n", utils.FormatDate(t.Date), t.From, t.To, strings.Join(t.CountedFor, " и ")))
+		}
+	}
+
+	builder.WriteString("\n")
+	switch r.Verdict {
+	case VerdictResident:
+		country, _ := splitRegion(r.Country)
+		flag := utils.CountryToFlag(utils.CountryCodeMap[country])
+		builder.WriteString(fmt.Sprintf("✅ Налоговый резидент: %s %s (%d дней)\n", flag, placeTitle(r.Country), r.Days))
+	case VerdictInconclusive:
+		builder.WriteString(fmt.Sprintf("❔ Статус не определён: %s\n", strings.Join(r.Reasons, "; ")))
+	default:
+		if r.Country != "" {
+			builder.WriteString(fmt.Sprintf("⚠️ Нет страны с >=%d днями. Больше всего в: %s (%d дней)\n", r.Threshold, placeTitle(r.Country), r.Days))
+		}
+	}
+	if len(r.GapFlips) > 0 {
+		builder.WriteString("\n⚠️ При другом учёте пробелов (/gaps) вывод меняется:\n")
+		for _, f := range r.GapFlips {
+			builder.WriteString(fmt.Sprintf("• %s: %s\n", GapStrategyTitle(f.Strategy), verdictTitle(f.Verdict, f.Country)))
+		}
+	}
+	if r.TieBreak != nil {
+		builder.WriteString(renderTieBreak(*r.TieBreak))
+	}
+
+	return builder.String()
+}
+
+// verdictTitle is a short description of a verdict for lists.
+func verdictTitle(verdict Verdict, country string) string {
+	switch verdict {
+	case VerdictResident:
+		return "резидент " + placeTitle(country)
+	case VerdictInconclusive:
+		return "статус не определён"
+	default:
+		return "нерезидент"
+	}
+}
+
+func renderTieBreak(tb TieBreak) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("\n🤝 Резидент двух стран: %s\n", strings.Join(tb.Countries, " и ")))
+	switch {
+	case tb.Country != "":
+		flag := utils.CountryToFlag(utils.CountryCodeMap[tb.Country])
+		builder.WriteString(fmt.Sprintf("⚖️ По налоговому соглашению (ст. 4 Модельной конвенции ОЭСР): резидент %s %s\n", flag, tb.Country))
+		builder.WriteString(fmt.Sprintf("Основание: %s\n", strings.Join(tb.Reasons, "; ")))
+	case tb.Next != "":
+		builder.WriteString("Ответьте на вопросы соглашения об избежании двойного налогообложения: /tiebreak\n")
+	default:
+		builder.WriteString(fmt.Sprintf("❔ %s\n", strings.Join(tb.Reasons, "; ")))
+	}
+	return builder.String()
+}
+
+type jsonCountry struct {
+	Country string `json:"country"`
+	Days    int    `json:"days"`
+}
+
+type jsonRegion struct {
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+	Days    int    `json:"days"`
+}
+
+type jsonError struct {
+	Code    string `json:"code,omitempty"`
+	Period  int    `json:"period,omitempty"`
+	Message string `json:"message"`
+}
+
+type jsonTravel struct {
+	Date       string   `json:"date"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	CountedFor []string `json:"counted_for"`
+}
+
+type jsonExcluded struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Country   string `json:"country"`
+	Purpose   string `json:"purpose,omitempty"`
+	Days      int    `json:"days"`
+	CountedAs string `json:"counted_as,omitempty"`
+}
+
+type jsonTieBreak struct {
+	Countries []string `json:"countries"`
+	Country   string   `json:"country,omitempty"`
+	Reasons   []string `json:"reasons,omitempty"`
+	Next      string   `json:"next,omitempty"`
+}
+
+type jsonGapFlip struct {
+	Strategy GapStrategy `json:"strategy"`
+	Verdict  Verdict     `json:"verdict"`
+	Country  string      `json:"country,omitempty"`
+}
+
+type jsonReport struct {
+	CalcDate    string         `json:"calc_date,omitempty"`
+	Rule        string         `json:"rule"`
+	From        string         `json:"from,omitempty"`
+	To          string         `json:"to,omitempty"`
+	Countries   []jsonCountry  `json:"countries"`
+	Regions     []jsonRegion   `json:"regions,omitempty"`
+	GapStrategy GapStrategy    `json:"gap_strategy,omitempty"`
+	GapFlips    []jsonGapFlip  `json:"gap_flips,omitempty"`
+	UnknownDays int            `json:"unknown_days"`
+	Verdict     Verdict        `json:"verdict,omitempty"`
+	Country     string         `json:"country,omitempty"`
+	Days        int            `json:"days"`
+	Threshold   int            `json:"threshold"`
+	Reasons     []string       `json:"reasons,omitempty"`
+	DayPolicy   DayPolicy      `json:"day_policy,omitempty"`
+	TravelDays  []jsonTravel   `json:"travel_days,omitempty"`
+	Excluded    []jsonExcluded `json:"excluded,omitempty"`
+	TieBreak    *jsonTieBreak  `json:"tie_break,omitempty"`
+	Errors      []jsonError    `json:"errors,omitempty"`
+}
+
+// RenderJSON formats the report for integrations; dates use ISO 8601.
+func RenderJSON(r Report) ([]byte, error) {
+	out := jsonReport{
+		Rule:        r.Rule,
+		Countries:   []jsonCountry{},
+		UnknownDays: r.UnknownDays,
+		Verdict:     r.Verdict,
+		Country:     r.Country,
+		Days:        r.Days,
+		Threshold:   r.Threshold,
+		Reasons:     r.Reasons,
+		DayPolicy:   r.DayPolicy,
+		GapStrategy: r.GapStrategy,
+	}
+	if !r.CalcDate.IsZero() {
+		out.CalcDate = r.CalcDate.Format("2006-01-02")
+	}
+	if !r.From.IsZero() {
+		out.From = r.From.Format("2006-01-02")
+		out.To = r.To.Format("2006-01-02")
+	}
+	for _, c := range r.Countries {
+		out.Countries = append(out.Countries, jsonCountry{Country: c.Country, Days: c.Days})
+	}
+	for _, rd := range r.Regions {
+		out.Regions = append(out.Regions, jsonRegion{Country: rd.Country, Region: rd.Region, Days: rd.Days})
+	}
+	for _, f := range r.GapFlips {
+		out.GapFlips = append(out.GapFlips, jsonGapFlip{Strategy: f.Strategy, Verdict: f.Verdict, Country: f.Country})
+	}
+	for _, t := range r.TravelDays {
+		out.TravelDays = append(out.TravelDays, jsonTravel{Date: t.Date.Format("2006-01-02"), From: t.From, To: t.To, CountedFor: t.CountedFor})
+	}
+	for _, e := range r.Excluded {
+		out.Excluded = append(out.Excluded, jsonExcluded{From: e.From.Format("2006-01-02"), To: e.To.Format("2006-01-02"), Country: e.Country, Purpose: e.Purpose, Days: e.Days, CountedAs: e.CountedAs})
+	}
+	if tb := r.TieBreak; tb != nil {
+		out.TieBreak = &jsonTieBreak{Countries: tb.Countries, Country: tb.Country, Reasons: tb.Reasons, Next: tb.Next}
+	}
+	for _, e := range r.Errors {
+		out.Errors = append(out.Errors, jsonError{Code: e.Code, Period: e.Period, Message: e.Message})
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// RenderCSV formats per-country day counts as CSV with a header row.
+func RenderCSV(r Report) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"country", "iso", "days"})
+	for _, c := range r.Countries {
+		_ = w.Write([]string{c.Country, utils.CountryCodeMap[c.Country], strconv.Itoa(c.Days)})
+	}
+	if r.UnknownDays > 0 {
+		_ = w.Write([]string{unknownCountry, "", strconv.Itoa(r.UnknownDays)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
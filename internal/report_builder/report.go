@@ -0,0 +1,63 @@
+package reportbuilder
+
+import "time"
+
+// Error codes reported in Report.Errors.
+const (
+	ErrChronology  = "chronology"
+	ErrInvalidDate = "invalid_date"
+)
+
+// ReportError is a problem that prevented building the report.
+type ReportError struct {
+	Code string
+	// Period is the 1-based number of the offending period, 0 if none.
+	Period  int
+	Message string
+}
+
+func (e *ReportError) Error() string {
+	return e.Message
+}
+
+// CountryDays is the number of days spent in a country.
+type CountryDays struct {
+	Country string
+	Days    int
+}
+
+// Report is the result of a residency calculation.
+type Report struct {
+	CalcDate  time.Time
+	Rule      string
+	From      time.Time
+	To        time.Time
+	Countries []CountryDays
+	// GapStrategy is how days between periods were attributed; GapFlips
+	// lists the other strategies that would change the verdict.
+	GapStrategy GapStrategy
+	GapFlips    []GapFlip
+	Regions     []RegionDays
+	UnknownDays int
+	Verdict     Verdict
+	Country     string
+	Days        int
+	Threshold   int
+	Reasons     []string
+	DayPolicy   DayPolicy
+	TravelDays  []TravelDay
+	Excluded    []ExcludedDays
+	// TieBreak is set when rules find residence in two countries.
+	TieBreak *TieBreak
+	Errors   []ReportError
+}
+
+// HasErrors reports whether the report could not be calculated.
+func (r Report) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// IsEmpty reports whether no days fall into the analysed window.
+func (r Report) IsEmpty() bool {
+	return len(r.Countries) == 0 && r.UnknownDays == 0
+}
@@ -1,6 +1,7 @@
 package reportbuilder
 
 import (
+	"strings"
 	"testing"
 
 	"telegram-tax-bot/internal/model"
@@ -11,9 +12,53 @@ func TestBuildReportSimple(t *testing.T) {
 		Current: "31.12.2023",
 		Periods: []model.Period{{In: "01.01.2023", Out: "31.12.2023", Country: "Россия"}},
 	}
-	got := BuildReport(data)
+	got := RenderText(BuildReport(data))
 	expected := "Анализ за период: 01.01.2023 — 31.12.2023\n\n🇷🇺 Россия: 365 дней\n\n✅ Налоговый резидент: 🇷🇺 Россия (365 дней)\n"
 	if got != expected {
 		t.Fatalf("unexpected report:\n%s", got)
 	}
 }
+
+func TestBuildReportChronologyError(t *testing.T) {
+	data := model.Data{
+		Current: "31.12.2023",
+		Periods: []model.Period{
+			{In: "01.06.2023", Out: "31.12.2023", Country: "Россия"},
+			{In: "01.01.2023", Out: "31.05.2023", Country: "Грузия"},
+		},
+	}
+	report := BuildReport(data)
+	if !report.HasErrors() || report.Errors[0].Code != ErrChronology || report.Errors[0].Period != 2 {
+		t.Fatalf("expected chronology error for period 2, got %+v", report.Errors)
+	}
+}
+
+func TestBuildReportInvalidDate(t *testing.T) {
+	data := model.Data{
+		Current: "31.12.2023",
+		Periods: []model.Period{
+			{In: "01.01.2023", Out: "31.05.2023", Country: "Россия"},
+			{In: "31.02.2023", Out: "31.12.2023", Country: "Грузия"},
+		},
+	}
+	report := BuildReport(data)
+	if !report.HasErrors() || report.Errors[0].Code != ErrInvalidDate || report.Errors[0].Period != 2 {
+		t.Fatalf("expected invalid date error for period 2, got %+v", report.Errors)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	data := model.Data{
+		Current: "31.12.2023",
+		Periods: []model.Period{{In: "01.01.2023", Out: "31.12.2023", Country: "Россия"}},
+	}
+	body, err := RenderJSON(BuildReport(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{`"from": "2023-01-01"`, `"verdict": "resident"`, `"days": 365`} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("expected %s in %s", want, body)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+// rollingRule counts days in the 12 months ending on the calculation date.
+type rollingRule struct {
+	threshold int
+}
+
+func init() {
+	RegisterRule(rollingRule{threshold: 183})
+}
+
+func (r rollingRule) Name() string {
+	return DefaultRule
+}
+
+func (rollingRule) rollingWindow() {}
+
+func (r rollingRule) Title() string {
+	return "12 месяцев подряд, 183 дня"
+}
+
+func (r rollingRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	oneYearAgo := calcDate.AddDate(-1, 0, 0).AddDate(0, 0, 1)
+	policy := PolicyFor(data, r)
+	spans, travel, err := resolveSpans(data, calcDate, oneYearAgo, policy)
+	if err != nil {
+		return RuleResult{}, err
+	}
+
+	spans, excluded := creditHome(spans, oneYearAgo, calcDate)
+	countryDays := countDays(spans, oneYearAgo, calcDate)
+	country, days := leader(countryDays)
+	result := RuleResult{
+		Rule:        r.Name(),
+		From:        oneYearAgo,
+		To:          calcDate,
+		CountryDays: countryDays,
+		Country:     country,
+		Days:        days,
+		Threshold:   r.threshold,
+		DayPolicy:   policy,
+		TravelDays:  travelIn(travel, oneYearAgo, calcDate),
+		Excluded:    excluded,
+	}
+	unknown := countryDays[unknownCountry]
+
+	switch {
+	case days >= r.threshold:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s: %d дней за 12 месяцев, порог %d", country, days, r.threshold))
+	case days+unknown >= r.threshold:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, fmt.Sprintf("неизвестно где %d дней — с ними порог %d может быть достигнут", unknown, r.threshold))
+	default:
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("ни одна страна не набрала %d дней", r.threshold))
+	}
+	return result, nil
+}
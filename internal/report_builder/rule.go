@@ -0,0 +1,71 @@
+package reportbuilder
+
+import (
+	"sort"
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+// Verdict is the outcome of a residency rule.
+type Verdict string
+
+const (
+	VerdictResident     Verdict = "resident"
+	VerdictNonResident  Verdict = "non_resident"
+	VerdictInconclusive Verdict = "inconclusive"
+)
+
+// RuleResult describes how a rule reached its verdict.
+type RuleResult struct {
+	Rule        string
+	From        time.Time
+	To          time.Time
+	CountryDays map[string]int
+	Country     string
+	Days        int
+	Threshold   int
+	Verdict     Verdict
+	Reasons     []string
+	DayPolicy   DayPolicy
+	TravelDays  []TravelDay
+	Excluded    []ExcludedDays
+}
+
+// ResidencyRule decides tax residency on a calculation date.
+type ResidencyRule interface {
+	// Name is a stable identifier stored in model.Data.Rule.
+	Name() string
+	// Title is shown to the user when choosing a rule.
+	Title() string
+	Evaluate(data model.Data, calcDate time.Time) (RuleResult, error)
+}
+
+// DefaultRule is used when model.Data.Rule is empty or unknown.
+const DefaultRule = "rolling_183"
+
+var rules = map[string]ResidencyRule{}
+
+// RegisterRule makes a rule available by its name.
+func RegisterRule(rule ResidencyRule) {
+	rules[rule.Name()] = rule
+}
+
+// RuleByName returns the registered rule or the default one.
+func RuleByName(name string) ResidencyRule {
+	if rule, ok := rules[name]; ok {
+		return rule
+	}
+	return rules[DefaultRule]
+}
+
+// Rules lists registered rules sorted by name.
+func Rules() []ResidencyRule {
+	list := make([]ResidencyRule, 0, len(rules))
+	for _, rule := range rules {
+		list = append(list, rule)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list
+}
@@ -0,0 +1,39 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestRuleByNameFallsBackToDefault(t *testing.T) {
+	if got := RuleByName("missing").Name(); got != DefaultRule {
+		t.Fatalf("expected default rule, got %s", got)
+	}
+	if len(Rules()) == 0 {
+		t.Fatal("expected registered rules")
+	}
+}
+
+func TestRollingRuleVerdicts(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2023")
+	cases := []struct {
+		name    string
+		periods []model.Period
+		verdict Verdict
+	}{
+		{"resident", []model.Period{{In: "01.01.2023", Out: "31.12.2023", Country: "Грузия"}}, VerdictResident},
+		{"non resident", []model.Period{{In: "01.01.2023", Out: "30.04.2023", Country: "Грузия"}, {In: "01.05.2023", Out: "31.08.2023", Country: "Армения"}, {In: "01.09.2023", Out: "31.12.2023", Country: "Турция"}}, VerdictNonResident},
+		{"inconclusive", []model.Period{{In: "01.01.2023", Out: "30.04.2023", Country: "Грузия"}, {In: "01.09.2023", Out: "31.12.2023", Country: "Турция"}}, VerdictInconclusive},
+	}
+	for _, c := range cases {
+		res, err := RuleByName(DefaultRule).Evaluate(model.Data{Periods: c.periods}, calcDate)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		if res.Verdict != c.verdict {
+			t.Fatalf("%s: expected %s, got %s (%v)", c.name, c.verdict, res.Verdict, res.Reasons)
+		}
+	}
+}
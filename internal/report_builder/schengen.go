@@ -0,0 +1,134 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+const (
+	schengenWindow = 180
+	schengenLimit  = 90
+)
+
+// SchengenStatus is the 90/180 short-stay balance on a date.
+type SchengenStatus struct {
+	Date time.Time
+	// From is the first day of the 180-day window ending on Date.
+	From      time.Time
+	Used      int
+	Remaining int
+	// Unknown days in the window may also have been spent in the area.
+	Unknown int
+}
+
+// schengenDays returns the days up to date spent in the Schengen area and
+// the unknown days, looking back far enough for any 180-day window that
+// ends within the next 180 days.
+func schengenDays(data model.Data, date time.Time) (map[time.Time]bool, map[time.Time]bool, error) {
+	windowStart := date.AddDate(0, 0, -(schengenWindow - 1))
+	// в шенгенской зоне дни въезда и выезда считаются всегда
+	spans, _, err := resolveSpans(data, date, windowStart, DayBoth)
+	if err != nil {
+		return nil, nil, err
+	}
+	used := make(map[time.Time]bool)
+	unknown := make(map[time.Time]bool)
+	for _, s := range spans {
+		var target map[time.Time]bool
+		switch {
+		case utils.Schengen.Contains(s.Country):
+			target = used
+		case s.Country == unknownCountry:
+			target = unknown
+		default:
+			continue
+		}
+		from, to := s.From, s.To
+		if from.Before(windowStart) {
+			from = windowStart
+		}
+		if to.After(date) {
+			to = date
+		}
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			target[d] = true
+		}
+	}
+	return used, unknown, nil
+}
+
+// usedInWindow counts days of the 180-day window ending on end.
+func usedInWindow(days map[time.Time]bool, end time.Time) int {
+	count := 0
+	for d := end.AddDate(0, 0, -(schengenWindow - 1)); !d.After(end); d = d.AddDate(0, 0, 1) {
+		if days[d] {
+			count++
+		}
+	}
+	return count
+}
+
+// Schengen calculates the 90/180 balance on date.
+func Schengen(data model.Data, date time.Time) (SchengenStatus, error) {
+	used, unknown, err := schengenDays(data, date)
+	if err != nil {
+		return SchengenStatus{}, err
+	}
+	st := SchengenStatus{
+		Date:    date,
+		From:    date.AddDate(0, 0, -(schengenWindow - 1)),
+		Used:    usedInWindow(used, date),
+		Unknown: usedInWindow(unknown, date),
+	}
+	if st.Used < schengenLimit {
+		st.Remaining = schengenLimit - st.Used
+	}
+	return st, nil
+}
+
+// SchengenEntryDate returns the earliest day after date on which a stay of
+// stay consecutive days keeps every 180-day window within 90 days, assuming
+// no other stays in the area after date.
+func SchengenEntryDate(data model.Data, date time.Time, stay int) (time.Time, error) {
+	if stay < 1 || stay > schengenLimit {
+		return time.Time{}, fmt.Errorf("длительность поездки должна быть от 1 до %d дней", schengenLimit)
+	}
+	used, _, err := schengenDays(data, date)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for entry := date.AddDate(0, 0, 1); ; entry = entry.AddDate(0, 0, 1) {
+		fits := true
+		for i := 0; i < stay; i++ {
+			day := entry.AddDate(0, 0, i)
+			if usedInWindow(used, day)+i+1 > schengenLimit {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return entry, nil
+		}
+	}
+}
+
+// RenderSchengen formats the balance and the earliest entry date.
+func RenderSchengen(st SchengenStatus, stay int, entry time.Time) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("🇪🇺 %s, правило 90/180\n", utils.Schengen.Name))
+	builder.WriteString(fmt.Sprintf("Окно: %s — %s\n\n", utils.FormatDate(st.From), utils.FormatDate(st.Date)))
+	builder.WriteString(fmt.Sprintf("Использовано: %d из %d дней\n", st.Used, schengenLimit))
+	builder.WriteString(fmt.Sprintf("Осталось: %d дней\n", st.Remaining))
+	if st.Used > schengenLimit {
+		builder.WriteString(fmt.Sprintf("⛔ Превышение: %d дней\n", st.Used-schengenLimit))
+	}
+	if st.Unknown > 0 {
+		builder.WriteString(fmt.Sprintf("🕳 Неизвестно где: %d дней — если это дни в зоне, остаток меньше\n", st.Unknown))
+	}
+	builder.WriteString(fmt.Sprintf("\n📅 Въехать на %d дней можно с %s\n", stay, utils.FormatDate(entry)))
+	return builder.String()
+}
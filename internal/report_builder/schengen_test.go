@@ -0,0 +1,43 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestSchengenSumsMemberStates(t *testing.T) {
+	date, _ := utils.ParseDate("30.06.2024")
+	data := model.Data{Periods: []model.Period{
+		{In: "01.03.2024", Out: "30.03.2024", Country: "Германия"},
+		{In: "31.03.2024", Out: "19.04.2024", Country: "Франция"},
+		{In: "20.04.2024", Out: "30.06.2024", Country: "Грузия"},
+	}}
+	st, err := Schengen(data, date)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.Used != 50 || st.Remaining != 40 {
+		t.Fatalf("expected 50 used and 40 remaining, got %d and %d", st.Used, st.Remaining)
+	}
+}
+
+func TestSchengenEntryDate(t *testing.T) {
+	date, _ := utils.ParseDate("30.06.2024")
+	data := model.Data{Periods: []model.Period{
+		{In: "01.03.2024", Out: "29.05.2024", Country: "Испания"},
+		{In: "30.05.2024", Out: "30.06.2024", Country: "Грузия"},
+	}}
+	// 90 дней использовано; 01.03 выпадает из окна 28.08
+	entry, err := SchengenEntryDate(data, date, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := utils.FormatDate(entry); got != "28.08.2024" {
+		t.Fatalf("expected 28.08.2024, got %s", got)
+	}
+	if _, err := SchengenEntryDate(data, date, 91); err == nil {
+		t.Fatal("expected error for stays over 90 days")
+	}
+}
@@ -0,0 +1,119 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+const usCountry = "США"
+
+// sptRule is the US Substantial Presence Test: at least 31 days in the
+// current year and at least 183 days in the weighted three-year sum
+// (current year + 1/3 of the prior year + 1/6 of the year before).
+type sptRule struct{}
+
+func init() {
+	RegisterRule(sptRule{})
+}
+
+func (sptRule) Name() string {
+	return "us_spt"
+}
+
+func (sptRule) Title() string {
+	return "США: Substantial Presence Test"
+}
+
+func (r sptRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	year := calcDate.Year()
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	firstYearStart := yearStart.AddDate(-2, 0, 0)
+	policy := PolicyFor(data, r)
+	spans, travel, err := resolveSpans(data, calcDate, firstYearStart, policy)
+	if err != nil {
+		return RuleResult{}, err
+	}
+
+	// дни в США и неизвестные дни по годам: [0] — текущий, [1] и [2] — предыдущие
+	var present, unknown [3]int
+	for i := range present {
+		from := yearStart.AddDate(-i, 0, 0)
+		to := from.AddDate(1, 0, -1)
+		if to.After(calcDate) {
+			to = calcDate
+		}
+		for _, s := range spans {
+			switch {
+			case s.Country == unknownCountry:
+				unknown[i] += s.daysIn(from, to)
+			case s.Country == usCountry && !sptExempt(s):
+				present[i] += s.daysIn(from, to)
+			}
+		}
+	}
+
+	// считаем в шестых долях дня, чтобы не терять дроби
+	weighted := 6*present[0] + 2*present[1] + present[2]
+	withUnknown := weighted + 6*unknown[0] + 2*unknown[1] + unknown[2]
+
+	result := RuleResult{
+		Rule:        r.Name(),
+		From:        yearStart,
+		To:          calcDate,
+		CountryDays: countDays(spans, yearStart, calcDate),
+		Country:     usCountry,
+		Days:        weighted / 6,
+		Threshold:   183,
+		DayPolicy:   policy,
+		TravelDays:  travelIn(travel, yearStart, calcDate),
+	}
+	for _, s := range spans {
+		if s.Country != usCountry || !sptExempt(s) {
+			continue
+		}
+		if days := s.daysIn(firstYearStart, calcDate); days > 0 {
+			result.Excluded = append(result.Excluded, ExcludedDays{From: s.From, To: s.To, Country: s.Country, Purpose: s.Period.Purpose, Days: days})
+		}
+	}
+	result.Reasons = append(result.Reasons, fmt.Sprintf("%d: %d дней, %d: %d дней, %d: %d дней; взвешенная сумма %d",
+		year, present[0], year-1, present[1], year-2, present[2], weighted/6))
+
+	met := present[0] >= 31 && weighted >= 6*183
+	switch {
+	case met && present[0] < 183 && closerConnection(spans, yearStart, calcDate):
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, "тест пройден, но заявлена более тесная связь с другой страной (closer connection)")
+	case met:
+		result.Verdict = VerdictResident
+	case present[0]+unknown[0] >= 31 && withUnknown >= 6*183:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, "с учётом дней «неизвестно где» тест может быть пройден")
+	default:
+		result.Verdict = VerdictNonResident
+		if present[0] < 31 {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("в текущем году меньше 31 дня (%d)", present[0]))
+		}
+	}
+	return result, nil
+}
+
+// sptExempt reports whether days of the span are not counted: exempt
+// individuals (F, J, M, Q visa students and teachers, diplomats) and the
+// medical condition exception. The purpose of the trip alone does not
+// exempt days: the student exemption needs the visa status and treatment
+// counts only for a condition that arose in the US.
+func sptExempt(s span) bool {
+	return s.Period.HasFlag(model.FlagExemptIndividual) || s.Period.HasFlag(model.FlagMedicalCondition)
+}
+
+// closerConnection reports whether any period overlapping [from, to]
+// claims a closer connection to a foreign country.
+func closerConnection(spans []span, from, to time.Time) bool {
+	for _, s := range spans {
+		if s.Period.HasFlag(model.FlagCloserConnection) && s.daysIn(from, to) > 0 {
+			return true
+		}
+	}
+	return false
+}
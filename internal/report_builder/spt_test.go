@@ -0,0 +1,57 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestSPTWeightedSum(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+	// 120 дней в 2024, 120 в 2023 (40), 120 в 2022 (20) — итого 180
+	data := model.Data{Periods: []model.Period{
+		{In: "01.01.2022", Out: "30.04.2022", Country: "США"},
+		{In: "01.05.2022", Out: "31.12.2022", Country: "Канада"},
+		{In: "01.01.2023", Out: "30.04.2023", Country: "США"},
+		{In: "01.05.2023", Out: "31.12.2023", Country: "Канада"},
+		{In: "01.01.2024", Out: "29.04.2024", Country: "США"},
+		{In: "30.04.2024", Out: "31.12.2024", Country: "Канада"},
+	}}
+	res, err := sptRule{}.Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Days != 180 || res.Verdict != VerdictNonResident {
+		t.Fatalf("expected 180 days and non-resident, got %d %s", res.Days, res.Verdict)
+	}
+
+	data.Periods[4].Out = "05.05.2024"
+	data.Periods[5].In = "06.05.2024"
+	res, _ = sptRule{}.Evaluate(data, calcDate)
+	if res.Verdict != VerdictResident {
+		t.Fatalf("expected resident, got %s (%v)", res.Verdict, res.Reasons)
+	}
+}
+
+func TestSPTExclusions(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+	data := model.Data{Periods: []model.Period{
+		{In: "01.01.2024", Out: "30.06.2024", Country: "США", Flags: []string{model.FlagExemptIndividual}},
+		{In: "01.07.2024", Out: "31.12.2024", Country: "Канада"},
+	}}
+	res, _ := sptRule{}.Evaluate(data, calcDate)
+	if res.Days != 0 || res.Verdict != VerdictNonResident {
+		t.Fatalf("exempt days must not count, got %d %s", res.Days, res.Verdict)
+	}
+
+	data.Periods = []model.Period{
+		{In: "01.01.2023", Out: "31.12.2023", Country: "США"},
+		{In: "01.01.2024", Out: "15.07.2024", Country: "Канада", Flags: []string{model.FlagCloserConnection}},
+		{In: "16.07.2024", Out: "31.12.2024", Country: "США"},
+	}
+	res, _ = sptRule{}.Evaluate(data, calcDate)
+	if res.Verdict != VerdictNonResident {
+		t.Fatalf("closer connection must apply below 183 days, got %s", res.Verdict)
+	}
+}
@@ -0,0 +1,224 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// TaxCalendar describes the day a country's tax year starts.
+type TaxCalendar struct {
+	Name       string
+	StartMonth time.Month
+	StartDay   int
+}
+
+// CalendarYear is the tax year of most countries.
+var CalendarYear = TaxCalendar{Name: "календарный год", StartMonth: time.January, StartDay: 1}
+
+// TaxCalendars lists countries whose tax year differs from the calendar year.
+var TaxCalendars = map[string]TaxCalendar{
+	"Великобритания": {Name: "6 апреля — 5 апреля", StartMonth: time.April, StartDay: 6},
+	"Австралия":      {Name: "1 июля — 30 июня", StartMonth: time.July, StartDay: 1},
+	"Новая Зеландия": {Name: "1 апреля — 31 марта", StartMonth: time.April, StartDay: 1},
+	"Индия":          {Name: "1 апреля — 31 марта", StartMonth: time.April, StartDay: 1},
+	"Гонконг":        {Name: "1 апреля — 31 марта", StartMonth: time.April, StartDay: 1},
+	"ЮАР":            {Name: "1 марта — 28/29 февраля", StartMonth: time.March, StartDay: 1},
+	"Пакистан":       {Name: "1 июля — 30 июня", StartMonth: time.July, StartDay: 1},
+	"Бангладеш":      {Name: "1 июля — 30 июня", StartMonth: time.July, StartDay: 1},
+}
+
+// CalendarFor returns the tax calendar of the country.
+func CalendarFor(country string) TaxCalendar {
+	if c, ok := TaxCalendars[country]; ok {
+		return c
+	}
+	return CalendarYear
+}
+
+// YearContaining returns the tax year containing date.
+func (c TaxCalendar) YearContaining(date time.Time) (time.Time, time.Time) {
+	start := time.Date(date.Year(), c.StartMonth, c.StartDay, 0, 0, 0, 0, time.UTC)
+	if date.Before(start) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start, start.AddDate(1, 0, -1)
+}
+
+// Year returns the tax year that ends in the given calendar year.
+func (c TaxCalendar) Year(year int) (time.Time, time.Time) {
+	end := time.Date(year, c.StartMonth, c.StartDay, 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	if c.StartMonth == time.January && c.StartDay == 1 {
+		end = end.AddDate(1, 0, 0)
+	}
+	return c.YearContaining(end)
+}
+
+// Label names the tax year starting on from: "2024" or "2023/24".
+func (c TaxCalendar) Label(from time.Time) string {
+	if from.Month() == time.January && from.Day() == 1 {
+		return fmt.Sprintf("%d", from.Year())
+	}
+	return fmt.Sprintf("%d/%02d", from.Year(), (from.Year()+1)%100)
+}
+
+// fiscalYearRule counts days in the tax year containing the calculation
+// date. With a zero calendar every country is judged by its own tax year.
+type fiscalYearRule struct {
+	calendar *TaxCalendar
+}
+
+// anyTwelveMonthsRule looks for 183 days within any 12 consecutive months
+// ending in the calendar year of the calculation date (Russia, Georgia).
+type anyTwelveMonthsRule struct{}
+
+func init() {
+	RegisterRule(fiscalYearRule{})
+	RegisterRule(anyTwelveMonthsRule{})
+}
+
+// FiscalYearRule evaluates the tax year of the given calendar.
+func FiscalYearRule(calendar TaxCalendar) ResidencyRule {
+	return fiscalYearRule{calendar: &calendar}
+}
+
+func (fiscalYearRule) Name() string {
+	return "fiscal_year_183"
+}
+
+func (r fiscalYearRule) Title() string {
+	if r.calendar != nil {
+		return fmt.Sprintf("Налоговый год (%s), 183 дня", r.calendar.Name)
+	}
+	return "Налоговый год страны, 183 дня"
+}
+
+func (r fiscalYearRule) Calendar() TaxCalendar {
+	if r.calendar != nil {
+		return *r.calendar
+	}
+	return CalendarYear
+}
+
+func (r fiscalYearRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	windowStart, _ := CalendarYear.YearContaining(calcDate)
+	for _, c := range TaxCalendars {
+		if from, _ := c.YearContaining(calcDate); from.Before(windowStart) {
+			windowStart = from
+		}
+	}
+	policy := PolicyFor(data, r)
+	spans, travel, err := resolveSpans(data, calcDate, windowStart, policy)
+	if err != nil {
+		return RuleResult{}, err
+	}
+
+	window := func(calendar TaxCalendar) (time.Time, time.Time) {
+		from, to := calendar.YearContaining(calcDate)
+		if to.After(calcDate) {
+			to = calcDate
+		}
+		return from, to
+	}
+
+	result := RuleResult{Rule: r.Name(), Threshold: 183}
+	if r.calendar != nil {
+		result.From, result.To = window(*r.calendar)
+		result.CountryDays = countDays(spans, result.From, result.To)
+		result.Country, result.Days = leader(result.CountryDays)
+	} else {
+		// каждая страна считается в своём налоговом году
+		seen := map[string]bool{}
+		for _, s := range spans {
+			if s.Country == unknownCountry || seen[s.Country] {
+				continue
+			}
+			seen[s.Country] = true
+			from, to := window(CalendarFor(s.Country))
+			days := countDays(spans, from, to)[s.Country]
+			if days > result.Days || (days == result.Days && s.Country < result.Country) {
+				result.Country, result.Days = s.Country, days
+				result.From, result.To = from, to
+			}
+		}
+		if result.Country == "" {
+			result.From, result.To = window(CalendarYear)
+		}
+		result.CountryDays = countDays(spans, result.From, result.To)
+	}
+
+	result.DayPolicy = policy
+	result.TravelDays = travelIn(travel, result.From, result.To)
+
+	calendar := CalendarFor(result.Country)
+	if r.calendar != nil {
+		calendar = *r.calendar
+	}
+	label := calendar.Label(result.From)
+	unknown := result.CountryDays[unknownCountry]
+	switch {
+	case result.Days >= result.Threshold:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s: %d дней в налоговом году %s", result.Country, result.Days, label))
+	case result.Days+unknown >= result.Threshold:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, fmt.Sprintf("неизвестно где %d дней в налоговом году %s", unknown, label))
+	default:
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("ни одна страна не набрала %d дней в налоговом году %s", result.Threshold, label))
+	}
+	return result, nil
+}
+
+func (anyTwelveMonthsRule) Name() string {
+	return "any_12_months_183"
+}
+
+func (anyTwelveMonthsRule) Title() string {
+	return "Любые 12 месяцев в году, 183 дня"
+}
+
+func (r anyTwelveMonthsRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	yearStart, yearEnd := CalendarYear.YearContaining(calcDate)
+	if yearEnd.After(calcDate) {
+		yearEnd = calcDate
+	}
+	policy := PolicyFor(data, r)
+	spans, travel, err := resolveSpans(data, calcDate, yearStart.AddDate(-1, 0, 1), policy)
+	if err != nil {
+		return RuleResult{}, err
+	}
+
+	result := RuleResult{Rule: r.Name(), Threshold: 183, DayPolicy: policy}
+	bestUnknown := 0
+	for end := yearStart; !end.After(yearEnd); end = end.AddDate(0, 0, 1) {
+		from := end.AddDate(-1, 0, 1)
+		counts := countDays(spans, from, end)
+		country, days := leader(counts)
+		if result.CountryDays == nil || days > result.Days {
+			result.From, result.To = from, end
+			result.CountryDays = counts
+			result.Country, result.Days = country, days
+		}
+		if counts[unknownCountry] > bestUnknown {
+			bestUnknown = counts[unknownCountry]
+		}
+	}
+
+	result.TravelDays = travelIn(travel, result.From, result.To)
+
+	switch {
+	case result.Days >= result.Threshold:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%s: %d дней за 12 месяцев %s — %s", result.Country, result.Days,
+			utils.FormatDate(result.From), utils.FormatDate(result.To)))
+	case result.Days+bestUnknown >= result.Threshold:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, fmt.Sprintf("неизвестно где до %d дней — с ними порог может быть достигнут", bestUnknown))
+	default:
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("ни в одном 12-месячном окне, заканчивающемся в %d году, нет %d дней", yearStart.Year(), result.Threshold))
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestTaxCalendars(t *testing.T) {
+	d, _ := utils.ParseDate("05.04.2024")
+	from, to := CalendarFor("Великобритания").YearContaining(d)
+	if utils.FormatDate(from) != "06.04.2023" || utils.FormatDate(to) != "05.04.2024" {
+		t.Fatalf("unexpected UK tax year %s — %s", utils.FormatDate(from), utils.FormatDate(to))
+	}
+
+	from, to = CalendarFor("Австралия").Year(2024)
+	if utils.FormatDate(from) != "01.07.2023" || utils.FormatDate(to) != "30.06.2024" {
+		t.Fatalf("unexpected AU tax year %s — %s", utils.FormatDate(from), utils.FormatDate(to))
+	}
+	if label := CalendarFor("Австралия").Label(from); label != "2023/24" {
+		t.Fatalf("unexpected label %s", label)
+	}
+
+	from, to = CalendarFor("Россия").Year(2024)
+	if utils.FormatDate(from) != "01.01.2024" || utils.FormatDate(to) != "31.12.2024" {
+		t.Fatalf("unexpected calendar year %s — %s", utils.FormatDate(from), utils.FormatDate(to))
+	}
+}
+
+func TestFiscalYearRule(t *testing.T) {
+	data := model.Data{Periods: []model.Period{
+		{In: "01.07.2023", Out: "31.03.2024", Country: "Австралия"},
+		{In: "01.04.2024", Out: "31.12.2024", Country: "Россия"},
+	}}
+	calcDate, _ := utils.ParseDate("30.06.2024")
+	res, err := RuleByName("fiscal_year_183").Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Country != "Австралия" || res.Days != 275 || res.Verdict != VerdictResident {
+		t.Fatalf("expected resident of Австралия with 275 days, got %s %d %s", res.Country, res.Days, res.Verdict)
+	}
+}
+
+func TestAnyTwelveMonthsRule(t *testing.T) {
+	// на 31.12.2023 ни одна страна не набрала 183 дня за последние 12 месяцев,
+	// но в окне 01.05.2022 — 30.04.2023 в Грузии 212 дней
+	data := model.Data{Periods: []model.Period{
+		{In: "01.10.2022", Out: "30.04.2023", Country: "Грузия"},
+		{In: "01.05.2023", Out: "31.08.2023", Country: "Турция"},
+		{In: "01.09.2023", Out: "31.12.2023", Country: "Армения"},
+	}}
+	calcDate, _ := utils.ParseDate("31.12.2023")
+	rolling, _ := RuleByName(DefaultRule).Evaluate(data, calcDate)
+	if rolling.Verdict != VerdictNonResident {
+		t.Fatalf("expected rolling rule to find no residency, got %s", rolling.Verdict)
+	}
+	res, err := RuleByName("any_12_months_183").Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Country != "Грузия" || res.Days != 212 || res.Verdict != VerdictResident {
+		t.Fatalf("expected Грузия with 212 days, got %s %d %s", res.Country, res.Days, res.Verdict)
+	}
+}
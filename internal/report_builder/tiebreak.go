@@ -0,0 +1,200 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+// Answer keys of the treaty tie-breaker (OECD Model Convention art. 4(2)).
+const (
+	// TieBreakPair stores the countries the answers were given for.
+	TieBreakPair        = "tiebreak.pair"
+	TieBreakHome        = "tiebreak.home"
+	TieBreakVital       = "tiebreak.vital"
+	TieBreakNationality = "tiebreak.nationality"
+)
+
+// Tie-breaker answers besides a country name.
+const (
+	AnswerBoth    = "both"
+	AnswerNeither = "neither"
+)
+
+// Option is a possible answer to a tie-breaker question.
+type Option struct {
+	Answer string
+	Title  string
+}
+
+// TieBreakQuestions lists the questions in the order of the convention.
+var TieBreakQuestions = []Question{
+	{TieBreakHome, "В какой стране у вас есть постоянное жильё — собственное или арендованное на длительный срок, доступное в любое время?"},
+	{TieBreakVital, "С какой страной теснее ваши личные и экономические связи (семья, работа, бизнес, имущество, банки)?"},
+	{TieBreakNationality, "Гражданином какой страны вы являетесь?"},
+}
+
+// TieBreakOptions returns the answers offered for the question.
+func TieBreakOptions(key string, countries []string) []Option {
+	var options []Option
+	for _, c := range countries {
+		options = append(options, Option{Answer: c, Title: c})
+	}
+	switch key {
+	case TieBreakHome:
+		options = append(options, Option{AnswerBoth, "🏠 В обеих"}, Option{AnswerNeither, "🚫 Ни в одной"})
+	case TieBreakVital:
+		options = append(options, Option{AnswerNeither, "🤷 Не могу определить"})
+	case TieBreakNationality:
+		options = append(options, Option{AnswerBoth, "🛂 Обеих стран"}, Option{AnswerNeither, "🚫 Ни одной из них"})
+	}
+	return options
+}
+
+// TieBreak is the treaty residence found by the art. 4(2) tie-breaker.
+type TieBreak struct {
+	Countries []string
+	// Country is the treaty residence; empty while undecided.
+	Country string
+	Reasons []string
+	// Next is the key of the question to answer next; empty when done.
+	Next string
+}
+
+// DualResidence returns the countries in which the compared rules find the
+// person resident on calcDate.
+func DualResidence(data model.Data, primary ResidencyRule, calcDate time.Time) []string {
+	seen := make(map[string]bool)
+	var countries []string
+	for _, rule := range ComparedRules(data, primary) {
+		result, err := rule.Evaluate(data, calcDate)
+		if err != nil || result.Verdict != VerdictResident || result.Country == "" || seen[result.Country] {
+			continue
+		}
+		// резидентство штата или провинции соглашения не регулируют
+		if _, region := splitRegion(result.Country); region != "" {
+			continue
+		}
+		seen[result.Country] = true
+		countries = append(countries, result.Country)
+	}
+	sort.Strings(countries)
+	return countries
+}
+
+// ComparedRules lists the rules checked for dual residence: the primary
+// rule (the one being reported), the second rule named by the user and the
+// UK test once its questionnaire is answered. Other registered rules are not
+// compared, as several of them describe the same country.
+func ComparedRules(data model.Data, primary ResidencyRule) []ResidencyRule {
+	list := []ResidencyRule{primary}
+	seen := map[string]bool{primary.Name(): true}
+	add := func(name string) {
+		if rule, ok := rules[name]; ok && !seen[name] {
+			seen[name] = true
+			list = append(list, rule)
+		}
+	}
+	add(data.SecondRule)
+	for key := range data.Answers {
+		if strings.HasPrefix(key, "uk.") {
+			add(ukSRTRule{}.Name())
+			break
+		}
+	}
+	return list
+}
+
+// PairKey identifies the pair of countries in model.Data.Answers.
+func PairKey(countries []string) string {
+	return strings.Join(countries, ";")
+}
+
+// ResolveTieBreak walks the art. 4(2) tests for the first two countries:
+// permanent home, centre of vital interests, habitual abode, nationality.
+// Habitual abode compares days in the two years ending on calcDate.
+func ResolveTieBreak(data model.Data, countries []string, calcDate time.Time) TieBreak {
+	if len(countries) > 2 {
+		countries = countries[:2]
+	}
+	tb := TieBreak{Countries: countries}
+	answers := data.Answers
+	if answers[TieBreakPair] != PairKey(countries) {
+		answers = nil
+	}
+	answer := func(key string) (string, bool) {
+		v, ok := answers[key]
+		return v, ok
+	}
+
+	home, ok := answer(TieBreakHome)
+	if !ok {
+		tb.Next = TieBreakHome
+		return tb
+	}
+	switch home {
+	case AnswerBoth:
+		tb.Reasons = append(tb.Reasons, "постоянное жильё есть в обеих странах")
+		vital, ok := answer(TieBreakVital)
+		if !ok {
+			tb.Next = TieBreakVital
+			return tb
+		}
+		if vital != AnswerNeither {
+			tb.Country = vital
+			tb.Reasons = append(tb.Reasons, fmt.Sprintf("центр жизненных интересов — %s", vital))
+			return tb
+		}
+		tb.Reasons = append(tb.Reasons, "центр жизненных интересов определить нельзя")
+	case AnswerNeither:
+		tb.Reasons = append(tb.Reasons, "постоянного жилья нет ни в одной из стран")
+	default:
+		tb.Country = home
+		tb.Reasons = append(tb.Reasons, fmt.Sprintf("постоянное жильё только в стране %s", home))
+		return tb
+	}
+
+	if country, reason, ok := habitualAbode(data, countries, calcDate); ok {
+		tb.Country = country
+		tb.Reasons = append(tb.Reasons, reason)
+		return tb
+	}
+	tb.Reasons = append(tb.Reasons, "обычное место жительства определить нельзя: дней поровну")
+
+	nationality, ok := answer(TieBreakNationality)
+	if !ok {
+		tb.Next = TieBreakNationality
+		return tb
+	}
+	if nationality != AnswerBoth && nationality != AnswerNeither {
+		tb.Country = nationality
+		tb.Reasons = append(tb.Reasons, fmt.Sprintf("гражданство — %s", nationality))
+		return tb
+	}
+	tb.Reasons = append(tb.Reasons, "гражданство не решает вопрос — резидентство определяют компетентные органы по взаимному согласию")
+	return tb
+}
+
+// habitualAbode picks the country with more days in the two years ending
+// on calcDate.
+func habitualAbode(data model.Data, countries []string, calcDate time.Time) (string, string, bool) {
+	if len(countries) < 2 {
+		return "", "", false
+	}
+	from := calcDate.AddDate(-2, 0, 1)
+	spans, _, err := resolveSpans(data, calcDate, from, PolicyFor(data, RuleByName(data.Rule)))
+	if err != nil {
+		return "", "", false
+	}
+	days := countDays(spans, from, calcDate)
+	a, b := countries[0], countries[1]
+	if days[a] == days[b] {
+		return "", "", false
+	}
+	if days[b] > days[a] {
+		a, b = b, a
+	}
+	return a, fmt.Sprintf("обычное место жительства — %s: %d дней за два года против %d", a, days[a], days[b]), true
+}
@@ -0,0 +1,97 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func dualData(answers map[string]string) model.Data {
+	return model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "01.07.2024", Country: "Россия"},
+			{In: "02.07.2024", Out: "31.12.2024", Country: usCountry},
+		},
+		Current:    "31.12.2024",
+		SecondRule: "us_spt",
+		Answers:    answers,
+	}
+}
+
+func TestDualResidence(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+
+	// без второго правила сравнивать не с чем, даже если другие правила
+	// называют другую страну
+	single := dualData(nil)
+	single.SecondRule = ""
+	if countries := DualResidence(single, RuleByName(single.Rule), calcDate); len(countries) != 1 {
+		t.Fatalf("expected the chosen rule only, got %v", countries)
+	}
+
+	countries := DualResidence(dualData(nil), RuleByName(DefaultRule), calcDate)
+	if len(countries) != 2 || countries[0] != "Россия" || countries[1] != usCountry {
+		t.Fatalf("unexpected countries %v", countries)
+	}
+
+	report := BuildReport(dualData(nil))
+	if report.TieBreak == nil || report.TieBreak.Next != TieBreakHome {
+		t.Fatalf("expected tie-breaker asking for home, got %+v", report.TieBreak)
+	}
+}
+
+func TestResolveTieBreak(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+	pair := PairKey([]string{"Россия", usCountry})
+
+	tests := []struct {
+		name    string
+		answers map[string]string
+		country string
+		next    string
+	}{
+		{"home decides", map[string]string{TieBreakPair: pair, TieBreakHome: usCountry}, usCountry, ""},
+		{"vital interests", map[string]string{TieBreakPair: pair, TieBreakHome: AnswerBoth, TieBreakVital: "Россия"}, "Россия", ""},
+		{"asks nationality", map[string]string{TieBreakPair: pair, TieBreakHome: AnswerBoth, TieBreakVital: AnswerNeither}, "", TieBreakNationality},
+		{"nationality", map[string]string{TieBreakPair: pair, TieBreakHome: AnswerNeither, TieBreakNationality: "Россия"}, "Россия", ""},
+		{"mutual agreement", map[string]string{TieBreakPair: pair, TieBreakHome: AnswerNeither, TieBreakNationality: AnswerBoth}, "", ""},
+		{"other pair", map[string]string{TieBreakPair: "Грузия;Россия", TieBreakHome: usCountry}, "", TieBreakHome},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := dualData(tt.answers)
+			tb := ResolveTieBreak(data, DualResidence(data, RuleByName(data.Rule), calcDate), calcDate)
+			if tb.Country != tt.country || tb.Next != tt.next {
+				t.Fatalf("expected %q/%q, got %q/%q (%v)", tt.country, tt.next, tb.Country, tb.Next, tb.Reasons)
+			}
+		})
+	}
+}
+
+func TestHabitualAbodeDecides(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2023", Out: "01.07.2024", Country: "Россия"},
+			{In: "02.07.2024", Out: "31.12.2024", Country: usCountry},
+		},
+		Current: "31.12.2024",
+		Answers: map[string]string{TieBreakPair: PairKey([]string{"Россия", usCountry}), TieBreakHome: AnswerNeither},
+	}
+	tb := ResolveTieBreak(data, []string{"Россия", usCountry}, calcDate)
+	if tb.Country != "Россия" {
+		t.Fatalf("expected Россия by habitual abode, got %q (%v)", tb.Country, tb.Reasons)
+	}
+}
+
+func TestComparedRules(t *testing.T) {
+	data := model.Data{Rule: "us_spt", SecondRule: "us_spt", Answers: map[string]string{UKFamilyTie: AnswerYes}}
+	var names []string
+	for _, rule := range ComparedRules(data, RuleByName(data.Rule)) {
+		names = append(names, rule.Name())
+	}
+	if len(names) != 2 || names[0] != "us_spt" || names[1] != "uk_srt" {
+		t.Fatalf("unexpected rules %v", names)
+	}
+}
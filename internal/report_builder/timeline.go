@@ -0,0 +1,104 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// Transition is a date on which a country crosses the threshold.
+type Transition struct {
+	Date    time.Time
+	Country string
+	// Gained is true when the country reaches the threshold, false when it
+	// drops below it.
+	Gained bool
+	Days   int
+}
+
+// Timeline lists residency changes under the rolling 12-month rule.
+type Timeline struct {
+	From      time.Time
+	To        time.Time
+	Threshold int
+	// Initial are the countries already at the threshold on From.
+	Initial     []string
+	Transitions []Transition
+}
+
+// BuildTimeline slides the 12-month window day by day from the start of the
+// data to the calculation date and records every threshold crossing.
+func BuildTimeline(data model.Data, threshold int) (Timeline, error) {
+	calcDate, err := utils.ParseDate(data.Current)
+	if err != nil {
+		return Timeline{}, &ReportError{Code: ErrInvalidDate, Message: fmt.Sprintf("некорректная дата расчёта «%s»", data.Current)}
+	}
+	tl := Timeline{To: calcDate, Threshold: threshold}
+	if len(data.Periods) == 0 {
+		return tl, nil
+	}
+
+	// при открытом начале первое окно целиком приходится на первый период
+	first := data.Periods[0]
+	ledgerStart, err := utils.ParseDate(first.In)
+	tl.From = ledgerStart
+	if err != nil {
+		firstOut, errOut := utils.ParseDate(first.Out)
+		if errOut != nil {
+			firstOut = calcDate
+		}
+		tl.From = firstOut
+		ledgerStart = firstOut.AddDate(-1, 0, 1)
+	}
+
+	spans, _, err := resolveSpans(data, calcDate, ledgerStart, PolicyFor(data, RuleByName(DefaultRule)))
+	if err != nil {
+		return tl, err
+	}
+	ledger := newLedger(spans, ledgerStart, calcDate)
+	countries := ledger.Countries()
+
+	resident := make(map[string]bool)
+	for day := tl.From; !day.After(calcDate); day = day.AddDate(0, 0, 1) {
+		windowStart := day.AddDate(-1, 0, 1)
+		for _, c := range countries {
+			days := ledger.Count(c, windowStart, day)
+			now := days >= threshold
+			if now == resident[c] {
+				continue
+			}
+			resident[c] = now
+			if day.Equal(tl.From) {
+				tl.Initial = append(tl.Initial, c)
+				continue
+			}
+			tl.Transitions = append(tl.Transitions, Transition{Date: day, Country: c, Gained: now, Days: days})
+		}
+	}
+	return tl, nil
+}
+
+// RenderTimeline formats the transitions as a Telegram message.
+func RenderTimeline(tl Timeline) string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("🧭 Изменения статуса (%d дней за 12 месяцев)\n", tl.Threshold))
+	builder.WriteString(fmt.Sprintf("Период: %s — %s\n\n", utils.FormatDate(tl.From), utils.FormatDate(tl.To)))
+	for _, c := range tl.Initial {
+		flag := utils.CountryToFlag(utils.CountryCodeMap[c])
+		builder.WriteString(fmt.Sprintf("▪️ на %s уже резидент: %s %s\n", utils.FormatDate(tl.From), flag, c))
+	}
+	for _, t := range tl.Transitions {
+		flag := utils.CountryToFlag(utils.CountryCodeMap[t.Country])
+		if t.Gained {
+			builder.WriteString(fmt.Sprintf("🟢 %s — резидент: %s %s\n", utils.FormatDate(t.Date), flag, t.Country))
+		} else {
+			builder.WriteString(fmt.Sprintf("🔴 %s — утрачено резидентство: %s %s\n", utils.FormatDate(t.Date), flag, t.Country))
+		}
+	}
+	if len(tl.Initial) == 0 && len(tl.Transitions) == 0 {
+		builder.WriteString("За весь период ни одна страна не набрала нужного числа дней.\n")
+	}
+	return builder.String()
+}
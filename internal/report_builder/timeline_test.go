@@ -0,0 +1,40 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	data := model.Data{
+		Current: "31.12.2024",
+		Periods: []model.Period{
+			{In: "01.01.2023", Out: "30.09.2023", Country: "Россия"},
+			{In: "01.10.2023", Out: "", Country: "Грузия"},
+		},
+	}
+	tl, err := BuildTimeline(data, 183)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []struct {
+		date    string
+		country string
+		gained  bool
+	}{
+		{"02.07.2023", "Россия", true},
+		{"31.03.2024", "Грузия", true},
+		{"01.04.2024", "Россия", false},
+	}
+	if len(tl.Transitions) != len(want) {
+		t.Fatalf("unexpected transitions: %+v", tl.Transitions)
+	}
+	for i, w := range want {
+		got := tl.Transitions[i]
+		if utils.FormatDate(got.Date) != w.date || got.Country != w.country || got.Gained != w.gained {
+			t.Fatalf("transition %d: expected %v, got %+v", i, w, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestDateLineCrossing(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "20.12.2023", Out: "02.01.2024", OutTime: "08:00", TimeZone: "Asia/Tokyo", Country: "Япония"},
+			{In: "01.01.2024", InTime: "20:00", TimeZone: "Pacific/Honolulu", Out: "10.01.2024", Country: usCountry},
+		},
+		Current: "10.01.2024",
+	}
+	report := BuildReport(data)
+	if report.HasErrors() {
+		t.Fatalf("unexpected errors %+v", report.Errors)
+	}
+	days := map[string]int{}
+	for _, c := range report.Countries {
+		days[c.Country] = c.Days
+	}
+	if days["Япония"] != 14 || days[usCountry] != 10 {
+		t.Fatalf("unexpected days %v", days)
+	}
+
+	data.Periods[1].InTime = ""
+	if report := BuildReport(data); !report.HasErrors() || report.Errors[0].Code != ErrChronology {
+		t.Fatalf("expected chronology error without times, got %+v", report.Errors)
+	}
+}
+
+func TestMidnightWithDepartureTime(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{
+			{In: "01.03.2024", Out: "10.03.2024", OutTime: "23:30", TimeZone: "Europe/Moscow", Country: "Россия"},
+			{In: "11.03.2024", InTime: "05:00", TimeZone: "Asia/Dubai", Out: "20.03.2024", Country: "ОАЭ"},
+		},
+		Current:   "20.03.2024",
+		DayPolicy: string(DayMidnight),
+	}
+	report := BuildReport(data)
+	days := map[string]int{}
+	for _, c := range report.Countries {
+		days[c.Country] = c.Days
+	}
+	if days["Россия"] != 9 || days["ОАЭ"] != 10 {
+		t.Fatalf("unexpected days %v", days)
+	}
+
+	data.DayPolicy = string(DayBoth)
+	for _, c := range BuildReport(data).Countries {
+		days[c.Country] = c.Days
+	}
+	if days["Россия"] != 10 {
+		t.Fatalf("expected 10 days in Россия with any-part counting, got %d", days["Россия"])
+	}
+}
+
+func TestInvalidTimeZone(t *testing.T) {
+	data := model.Data{
+		Periods: []model.Period{{In: "01.03.2024", InTime: "10:00", TimeZone: "Europe/Atlantis", Out: "10.03.2024", Country: "Россия"}},
+		Current: "10.03.2024",
+	}
+	report := BuildReport(data)
+	if !report.HasErrors() || report.Errors[0].Code != ErrInvalidDate || report.Errors[0].Period != 1 {
+		t.Fatalf("expected invalid date error, got %+v", report.Errors)
+	}
+}
@@ -0,0 +1,285 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+const ukCountry = "Великобритания"
+
+// Questions of the UK Statutory Residence Test questionnaire. The facts
+// change from year to year, so the answers are stored per tax year under
+// UKAnswerKey.
+const (
+	UKResidentBefore   = "uk.resident_before"
+	UKFullTimeOverseas = "uk.full_time_overseas"
+	UKOnlyHome         = "uk.only_home"
+	UKFullTimeUK       = "uk.full_time_uk"
+	UKFamilyTie        = "uk.family"
+	UKAccommodationTie = "uk.accommodation"
+	UKWorkTie          = "uk.work"
+)
+
+// Question is a yes/no question whose answer is stored in model.Data.Answers.
+type Question struct {
+	Key  string
+	Text string
+}
+
+// UKQuestions gathers the facts the SRT needs that periods cannot provide.
+var UKQuestions = []Question{
+	{UKResidentBefore, "Были ли вы налоговым резидентом Великобритании хотя бы в одном из трёх предыдущих налоговых лет?"},
+	{UKFullTimeOverseas, "Работали ли вы полный рабочий день за рубежом весь налоговый год (меньше 31 рабочего дня в Великобритании)?"},
+	{UKOnlyHome, "Был ли у вас дом только в Великобритании (91+ дней, из них 30+ дней в налоговом году вы там бывали)?"},
+	{UKFullTimeUK, "Работали ли вы полный рабочий день в Великобритании 365 дней подряд?"},
+	{UKFamilyTie, "Есть ли у вас супруг(а), партнёр или несовершеннолетние дети — резиденты Великобритании?"},
+	{UKAccommodationTie, "Было ли у вас доступное жильё в Великобритании 91+ дней подряд, где вы провели хотя бы одну ночь?"},
+	{UKWorkTie, "Работали ли вы в Великобритании 40+ дней (более 3 часов в день)?"},
+}
+
+// UKAnswerKey returns the key of the answer to the question for the UK tax
+// year starting on from, e.g. "uk.2024/25.family".
+func UKAnswerKey(question string, from time.Time) string {
+	return "uk." + CalendarFor(ukCountry).Label(from) + "." + strings.TrimPrefix(question, "uk.")
+}
+
+// UKQuestionsFor returns the questionnaire for the UK tax year starting on
+// from, keyed by UKAnswerKey.
+func UKQuestionsFor(from time.Time) []Question {
+	questions := make([]Question, len(UKQuestions))
+	for i, q := range UKQuestions {
+		questions[i] = Question{Key: UKAnswerKey(q.Key, from), Text: q.Text}
+	}
+	return questions
+}
+
+// ukAnswers picks the answers for the tax year starting on from, keyed by
+// question.
+func ukAnswers(answers map[string]string, from time.Time) map[string]string {
+	year := make(map[string]string)
+	for _, q := range UKQuestions {
+		if v, ok := answers[UKAnswerKey(q.Key, from)]; ok {
+			year[q.Key] = v
+		}
+	}
+	return year
+}
+
+// Answer values stored in model.Data.Answers.
+const (
+	AnswerYes = "yes"
+	AnswerNo  = "no"
+)
+
+// ukSRTRule is the UK Statutory Residence Test for the tax year
+// (6 April – 5 April) containing the calculation date.
+type ukSRTRule struct{}
+
+func init() {
+	RegisterRule(ukSRTRule{})
+}
+
+func (ukSRTRule) Name() string {
+	return "uk_srt"
+}
+
+func (ukSRTRule) Title() string {
+	return "Великобритания: Statutory Residence Test"
+}
+
+// DayPolicy follows the SRT: a day counts if you are in the UK at midnight.
+func (ukSRTRule) DayPolicy() DayPolicy {
+	return DayMidnight
+}
+
+func (ukSRTRule) Calendar() TaxCalendar {
+	return CalendarFor(ukCountry)
+}
+
+func (r ukSRTRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	from, end := CalendarFor(ukCountry).YearContaining(calcDate)
+	to := end
+	if to.After(calcDate) {
+		to = calcDate
+	}
+	policy := PolicyFor(data, r)
+	spans, travel, err := resolveSpans(data, calcDate, from.AddDate(-3, 0, 0), policy)
+	if err != nil {
+		return RuleResult{}, err
+	}
+
+	countryDays := countDays(spans, from, to)
+	// исключительные обстоятельства — не больше 60 дней за налоговый год
+	exceptional, excluded := excludeCapped(spans, ukCountry, model.PurposeForceMajeure, ukExceptionalLimit, from, to)
+	countryDays[ukCountry] -= exceptional
+	in := srtInput{
+		days:    countryDays[ukCountry],
+		unknown: countryDays[unknownCountry],
+		answers: ukAnswers(data.Answers, from),
+	}
+
+	// резидентство в прошлых годах и связь «90 дней» считаем по периодам
+	var residentComputed bool
+	for i := 1; i <= 3; i++ {
+		days := countDays(spans, from.AddDate(-i, 0, 0), from.AddDate(-i+1, 0, -1))[ukCountry]
+		if days >= 183 {
+			residentComputed = true
+		}
+		if i <= 2 && days > 90 {
+			in.tie90 = true
+		}
+	}
+	in.residentBefore = residentComputed
+	if v, ok := in.answers[UKResidentBefore]; ok {
+		in.residentBefore = v == AnswerYes
+	}
+
+	in.countryTie = true
+	for c, d := range countryDays {
+		if c != ukCountry && c != unknownCountry && d > in.days {
+			in.countryTie = false
+		}
+	}
+
+	low, lowReasons := in.decide(false)
+	high, highReasons := in.decide(true)
+
+	result := RuleResult{
+		Rule:        r.Name(),
+		From:        from,
+		To:          to,
+		CountryDays: countryDays,
+		Country:     ukCountry,
+		Days:        in.days,
+		Threshold:   183,
+		DayPolicy:   policy,
+		TravelDays:  travelIn(travel, from, to),
+		Excluded:    excluded,
+	}
+	switch {
+	case low == VerdictResident:
+		result.Verdict = VerdictResident
+		result.Reasons = lowReasons
+	case high == VerdictNonResident:
+		result.Verdict = VerdictNonResident
+		result.Reasons = highReasons
+	default:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, "результат зависит от неизвестных дней или неотвеченных вопросов")
+		if missing := in.missing(); len(missing) > 0 {
+			result.Reasons = append(result.Reasons, fmt.Sprintf("ответьте на вопросы за %s год (/uk %d): %d из %d", CalendarFor(ukCountry).Label(from), end.Year(), len(missing), len(UKQuestions)))
+		}
+	}
+	return result, nil
+}
+
+// srtInput holds the facts the SRT decision is based on.
+type srtInput struct {
+	days           int
+	unknown        int
+	residentBefore bool
+	tie90          bool
+	countryTie     bool
+	answers        map[string]string
+}
+
+// answer returns the stored answer; unanswered questions are assumed to
+// favour residency when favourResident is set.
+func (in srtInput) answer(key string, favourResident bool) bool {
+	if v, ok := in.answers[key]; ok {
+		return v == AnswerYes
+	}
+	if key == UKFullTimeOverseas {
+		return !favourResident
+	}
+	return favourResident
+}
+
+func (in srtInput) missing() []string {
+	var keys []string
+	for _, q := range UKQuestions {
+		if _, ok := in.answers[q.Key]; !ok {
+			keys = append(keys, q.Key)
+		}
+	}
+	return keys
+}
+
+// decide applies the SRT steps in order. With favourResident the unknown
+// days are counted in the UK and open questions are answered towards
+// residency, giving the upper bound of the outcome.
+func (in srtInput) decide(favourResident bool) (Verdict, []string) {
+	days := in.days
+	if favourResident {
+		days += in.unknown
+	}
+
+	// автоматические тесты нерезидентства
+	switch {
+	case in.residentBefore && days < 16:
+		return VerdictNonResident, []string{fmt.Sprintf("автоматический тест нерезидентства: %d дней при пороге 16", days)}
+	case !in.residentBefore && days < 46:
+		return VerdictNonResident, []string{fmt.Sprintf("автоматический тест нерезидентства: %d дней при пороге 46", days)}
+	case in.answer(UKFullTimeOverseas, favourResident) && days < 91:
+		return VerdictNonResident, []string{"автоматический тест нерезидентства: полная занятость за рубежом"}
+	}
+
+	// автоматические тесты резидентства
+	switch {
+	case days >= 183:
+		return VerdictResident, []string{fmt.Sprintf("автоматический тест резидентства: %d дней", days)}
+	case in.answer(UKOnlyHome, favourResident):
+		return VerdictResident, []string{"автоматический тест резидентства: единственный дом в Великобритании"}
+	case in.answer(UKFullTimeUK, favourResident):
+		return VerdictResident, []string{"автоматический тест резидентства: полная занятость в Великобритании"}
+	}
+
+	// достаточные связи
+	var ties []string
+	if in.answer(UKFamilyTie, favourResident) {
+		ties = append(ties, "семья")
+	}
+	if in.answer(UKAccommodationTie, favourResident) {
+		ties = append(ties, "жильё")
+	}
+	if in.answer(UKWorkTie, favourResident) {
+		ties = append(ties, "работа")
+	}
+	if in.tie90 {
+		ties = append(ties, "90 дней")
+	}
+	if in.residentBefore && in.countryTie {
+		ties = append(ties, "страна")
+	}
+
+	needed := sufficientTies(days, in.residentBefore)
+	reason := fmt.Sprintf("%d дней, связей: %d (%s), нужно: %d", days, len(ties), strings.Join(ties, ", "), needed)
+	if len(ties) >= needed {
+		return VerdictResident, []string{reason}
+	}
+	return VerdictNonResident, []string{reason}
+}
+
+// sufficientTies returns how many UK ties make a person resident for the
+// given number of days; arrivers were not resident in the previous three
+// tax years, leavers were.
+func sufficientTies(days int, leaver bool) int {
+	switch {
+	case leaver && days <= 45:
+		return 4
+	case leaver && days <= 90:
+		return 3
+	case leaver && days <= 120:
+		return 2
+	case leaver:
+		return 1
+	case days <= 90:
+		return 4
+	case days <= 120:
+		return 3
+	default:
+		return 2
+	}
+}
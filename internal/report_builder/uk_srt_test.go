@@ -0,0 +1,74 @@
+package reportbuilder
+
+import (
+	"strings"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func TestUKSRT(t *testing.T) {
+	calcDate, _ := utils.ParseDate("05.04.2024")
+	data := model.Data{Periods: []model.Period{
+		{In: "06.04.2023", Out: "14.07.2023", Country: "Великобритания"},
+		{In: "15.07.2023", Out: "05.04.2024", Country: "Франция"},
+	}}
+
+	// 100 дней, прибывший, ответов нет — исход зависит от связей
+	res, _ := ukSRTRule{}.Evaluate(data, calcDate)
+	if res.Days != 100 || res.Verdict != VerdictInconclusive {
+		t.Fatalf("expected 100 days and inconclusive, got %d %s", res.Days, res.Verdict)
+	}
+
+	from, _ := utils.ParseDate("06.04.2023")
+	answer := func(question, value string) {
+		data.Answers[UKAnswerKey(question, from)] = value
+	}
+	data.Answers = map[string]string{}
+	for _, q := range UKQuestions {
+		answer(q.Key, AnswerNo)
+	}
+	answer(UKFamilyTie, AnswerYes)
+	answer(UKAccommodationTie, AnswerYes)
+	res, _ = ukSRTRule{}.Evaluate(data, calcDate)
+	if res.Verdict != VerdictNonResident {
+		t.Fatalf("two ties are not enough for 100 days, got %s (%v)", res.Verdict, res.Reasons)
+	}
+
+	answer(UKWorkTie, AnswerYes)
+	res, _ = ukSRTRule{}.Evaluate(data, calcDate)
+	if res.Verdict != VerdictResident {
+		t.Fatalf("three ties are enough for 100 days, got %s (%v)", res.Verdict, res.Reasons)
+	}
+}
+
+func TestUKSRTAnswersPerTaxYear(t *testing.T) {
+	data := model.Data{Periods: []model.Period{
+		{In: "06.04.2020", Out: "05.04.2023", Country: "Великобритания"},
+		{In: "06.04.2023", Out: "05.04.2025", Country: "Франция"},
+	}}
+	from, _ := utils.ParseDate("06.04.2023")
+	data.Answers = map[string]string{UKAnswerKey(UKResidentBefore, from): AnswerNo}
+	if key := UKAnswerKey(UKResidentBefore, from); key != "uk.2023/24.resident_before" {
+		t.Fatalf("unexpected key %s", key)
+	}
+
+	// ответ за 2023/24 не переносится на 2024/25: там прошлые годы считаются по периодам
+	calcDate, _ := utils.ParseDate("05.04.2025")
+	res, _ := ukSRTRule{}.Evaluate(data, calcDate)
+	if res.Verdict != VerdictNonResident || !strings.Contains(strings.Join(res.Reasons, "; "), "пороге 16") {
+		t.Fatalf("expected the leaver threshold for 2024/25, got %s (%v)", res.Verdict, res.Reasons)
+	}
+	calcDate, _ = utils.ParseDate("05.04.2024")
+	res, _ = ukSRTRule{}.Evaluate(data, calcDate)
+	if !strings.Contains(strings.Join(res.Reasons, "; "), "пороге 46") {
+		t.Fatalf("expected the arriver threshold for 2023/24, got %v", res.Reasons)
+	}
+}
+
+func TestSufficientTies(t *testing.T) {
+	if sufficientTies(30, true) != 4 || sufficientTies(150, true) != 1 || sufficientTies(150, false) != 2 {
+		t.Fatal("unexpected ties table")
+	}
+}
@@ -0,0 +1,142 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"telegram-tax-bot/internal/model"
+	"time"
+)
+
+// Answer keys of the US state residency questions.
+const (
+	NYDomicile   = "ny.domicile"
+	NYAbode      = "ny.abode"
+	CADomicile   = "ca.domicile"
+	CASafeHarbor = "ca.safe_harbor"
+)
+
+// StateQuestions gathers the facts state rules need that periods cannot provide.
+var StateQuestions = []Question{
+	{NYDomicile, "Нью-Йорк — ваш домицилий (постоянный дом, куда вы намерены вернуться)?"},
+	{NYAbode, "Было ли у вас постоянное жильё в Нью-Йорке почти весь год (больше 11 месяцев)?"},
+	{CADomicile, "Калифорния — ваш домицилий?"},
+	{CASafeHarbor, "Работали ли вы по трудовому контракту за пределами Калифорнии не меньше 546 дней подряд?"},
+}
+
+// stateDays counts days in the region in the calendar year containing
+// calcDate; any part of a day counts.
+func stateDays(data model.Data, calcDate time.Time, country, region string) (RuleResult, int, error) {
+	from := time.Date(calcDate.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	spans, err := regionSpans(data, calcDate, from)
+	if err != nil {
+		return RuleResult{}, 0, err
+	}
+	key := regionKey(country, region)
+	days := countDays(spans, from, calcDate)
+	countrySpans, travel, err := resolveSpans(data, calcDate, from, DayBoth)
+	if err != nil {
+		return RuleResult{}, 0, err
+	}
+	result := RuleResult{
+		From:        from,
+		To:          calcDate,
+		CountryDays: countDays(countrySpans, from, calcDate),
+		Country:     key,
+		Days:        days[key],
+		DayPolicy:   DayBoth,
+		TravelDays:  travelIn(travel, from, calcDate),
+	}
+	return result, days[unknownCountry], nil
+}
+
+// nyRule is the New York statutory residence test: domicile, or a
+// permanent place of abode and more than 183 days in the state.
+type nyRule struct{}
+
+// caRule is the California residency test: domicile unless the employment
+// safe harbor applies, or the presumption after nine months in the state.
+type caRule struct{}
+
+func init() {
+	RegisterRule(nyRule{})
+	RegisterRule(caRule{})
+}
+
+func (nyRule) Name() string {
+	return "us_ny"
+}
+
+func (nyRule) Title() string {
+	return "США: штат Нью-Йорк"
+}
+
+func (nyRule) DayPolicy() DayPolicy {
+	return DayBoth
+}
+
+func (r nyRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	result, unknown, err := stateDays(data, calcDate, usCountry, "NY")
+	if err != nil {
+		return RuleResult{}, err
+	}
+	result.Rule = r.Name()
+	result.Threshold = 184
+
+	domicile, abode := data.Answers[NYDomicile], data.Answers[NYAbode]
+	switch {
+	case domicile == AnswerYes:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, "домицилий в Нью-Йорке")
+	case abode == AnswerYes && result.Days >= result.Threshold:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("постоянное жильё и %d дней в штате (больше 183)", result.Days))
+	case domicile == AnswerNo && (abode == AnswerNo || result.Days+unknown < result.Threshold):
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("нет домицилия; %d дней в штате", result.Days))
+	default:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, "ответьте на вопросы о домицилии и жилье (/state) или уточните дни «неизвестно где»")
+	}
+	return result, nil
+}
+
+func (caRule) Name() string {
+	return "us_ca"
+}
+
+func (caRule) Title() string {
+	return "США: штат Калифорния"
+}
+
+func (caRule) DayPolicy() DayPolicy {
+	return DayBoth
+}
+
+func (r caRule) Evaluate(data model.Data, calcDate time.Time) (RuleResult, error) {
+	result, unknown, err := stateDays(data, calcDate, usCountry, "CA")
+	if err != nil {
+		return RuleResult{}, err
+	}
+	result.Rule = r.Name()
+	// больше девяти месяцев налогового года
+	result.Threshold = daysBetween(result.From, result.From.AddDate(0, 9, -1)) + 1
+
+	domicile, safeHarbor := data.Answers[CADomicile], data.Answers[CASafeHarbor]
+	switch {
+	case domicile == AnswerYes && safeHarbor == AnswerYes:
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, "работа за пределами штата 546+ дней подряд (safe harbor)")
+	case domicile == AnswerYes && safeHarbor == AnswerNo:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, "домицилий в Калифорнии, отсутствие временное")
+	case result.Days >= result.Threshold:
+		result.Verdict = VerdictResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("презумпция резидентства: %d дней в штате, больше девяти месяцев", result.Days))
+	case domicile == AnswerNo && result.Days+unknown < result.Threshold:
+		result.Verdict = VerdictNonResident
+		result.Reasons = append(result.Reasons, fmt.Sprintf("нет домицилия; %d дней в штате", result.Days))
+	default:
+		result.Verdict = VerdictInconclusive
+		result.Reasons = append(result.Reasons, "ответьте на вопросы о домицилии (/state) или уточните дни «неизвестно где»")
+	}
+	return result, nil
+}
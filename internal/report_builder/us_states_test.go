@@ -0,0 +1,78 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+)
+
+func statesData(answers map[string]string) model.Data {
+	return model.Data{
+		Periods: []model.Period{
+			{In: "01.01.2024", Out: "30.06.2024", Country: usCountry, Region: "NY"},
+			{In: "30.06.2024", Out: "31.12.2024", Country: usCountry, Region: "California"},
+		},
+		Current: "31.12.2024",
+		Answers: answers,
+	}
+}
+
+func TestRegionBreakdown(t *testing.T) {
+	report := BuildReport(statesData(nil))
+	if len(report.Countries) != 1 || report.Countries[0].Days != 366 {
+		t.Fatalf("unexpected countries %+v", report.Countries)
+	}
+	want := []RegionDays{
+		{Country: usCountry, Region: "Калифорния", Days: 185},
+		{Country: usCountry, Region: "Нью-Йорк", Days: 182},
+	}
+	if len(report.Regions) != len(want) {
+		t.Fatalf("unexpected regions %+v", report.Regions)
+	}
+	for i := range want {
+		if report.Regions[i] != want[i] {
+			t.Fatalf("expected %+v, got %+v", want[i], report.Regions[i])
+		}
+	}
+}
+
+func TestStateRules(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+	tests := []struct {
+		rule    string
+		answers map[string]string
+		want    Verdict
+	}{
+		{"us_ny", nil, VerdictInconclusive},
+		{"us_ny", map[string]string{NYDomicile: AnswerNo, NYAbode: AnswerYes}, VerdictNonResident},
+		{"us_ny", map[string]string{NYDomicile: AnswerYes}, VerdictResident},
+		{"us_ca", map[string]string{CADomicile: AnswerNo}, VerdictNonResident},
+		{"us_ca", map[string]string{CADomicile: AnswerYes, CASafeHarbor: AnswerYes}, VerdictNonResident},
+		{"us_ca", map[string]string{CADomicile: AnswerYes, CASafeHarbor: AnswerNo}, VerdictResident},
+	}
+	for _, tt := range tests {
+		result, err := RuleByName(tt.rule).Evaluate(statesData(tt.answers), calcDate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Verdict != tt.want {
+			t.Fatalf("%s %v: expected %s, got %s (%v)", tt.rule, tt.answers, tt.want, result.Verdict, result.Reasons)
+		}
+	}
+}
+
+func TestCaliforniaPresumption(t *testing.T) {
+	calcDate, _ := utils.ParseDate("31.12.2024")
+	data := model.Data{
+		Periods: []model.Period{{In: "01.01.2024", Out: "31.10.2024", Country: usCountry, Region: "CA"}},
+		Current: "31.12.2024",
+	}
+	result, err := RuleByName("us_ca").Evaluate(data, calcDate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != VerdictResident || result.Country != regionKey(usCountry, "CA") {
+		t.Fatalf("expected California resident, got %s %s", result.Verdict, result.Country)
+	}
+}
@@ -0,0 +1,191 @@
+package reportbuilder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// calendarRule is implemented by rules tied to a tax calendar.
+type calendarRule interface {
+	Calendar() TaxCalendar
+}
+
+// CalendarOf returns the tax calendar the rule works with.
+func CalendarOf(rule ResidencyRule) TaxCalendar {
+	if r, ok := rule.(calendarRule); ok {
+		return r.Calendar()
+	}
+	return CalendarYear
+}
+
+// rollingWindowRule is implemented by rules whose window ends on the
+// calculation date and has nothing to do with the tax year; /years judges
+// them by the year's own days. Rules that look for a window inside the tax
+// year, such as any_12_months_183, are evaluated at the year end.
+type rollingWindowRule interface {
+	rollingWindow()
+}
+
+// yearThreshold is the tax year day count of rules judged by their rows.
+const yearThreshold = 183
+
+// YearRow is the residency status for one tax year.
+type YearRow struct {
+	Label       string
+	From        time.Time
+	To          time.Time
+	Countries   []CountryDays
+	UnknownDays int
+	Verdict     Verdict
+	Country     string
+	Days        int
+	// Incomplete is set when the year has unknown days or is not fully
+	// covered by the periods.
+	Incomplete bool
+	Notes      []string
+}
+
+// BuildYearsReport evaluates the rule for every tax year of the calendar
+// covered by the periods, up to the calculation date.
+func BuildYearsReport(data model.Data, rule ResidencyRule, calendar TaxCalendar) ([]YearRow, error) {
+	calcDate, err := utils.ParseDate(data.Current)
+	if err != nil {
+		return nil, &ReportError{Code: ErrInvalidDate, Message: fmt.Sprintf("некорректная дата расчёта «%s»", data.Current)}
+	}
+	if len(data.Periods) == 0 {
+		return nil, nil
+	}
+
+	first := data.Periods[0]
+	coveredFrom, err := utils.ParseDate(first.In)
+	if err != nil {
+		// открытое начало: данные покрывают год, в котором закончился первый период
+		firstOut, errOut := utils.ParseDate(first.Out)
+		if errOut != nil {
+			firstOut = calcDate
+		}
+		coveredFrom, _ = calendar.YearContaining(firstOut)
+	}
+	coveredTo := calcDate
+	if last := data.Periods[len(data.Periods)-1]; last.Out != "" {
+		if lastOut, err := utils.ParseDate(last.Out); err == nil && lastOut.Before(calcDate) {
+			coveredTo = lastOut
+		}
+	}
+
+	firstYear, _ := calendar.YearContaining(coveredFrom)
+	spans, _, err := resolveSpans(data, calcDate, firstYear, PolicyFor(data, rule))
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []YearRow
+	for from := firstYear; !from.After(coveredTo); from = from.AddDate(1, 0, 0) {
+		to := from.AddDate(1, 0, -1)
+		row := YearRow{Label: calendar.Label(from), From: from, To: to}
+
+		evalDate := to
+		if evalDate.After(calcDate) {
+			evalDate = calcDate
+		}
+		for c, d := range countDays(spans, from, evalDate) {
+			if d == 0 {
+				continue
+			}
+			if c == unknownCountry {
+				row.UnknownDays = d
+				continue
+			}
+			row.Countries = append(row.Countries, CountryDays{Country: c, Days: d})
+		}
+		sort.Slice(row.Countries, func(i, j int) bool {
+			if row.Countries[i].Days == row.Countries[j].Days {
+				return row.Countries[i].Country < row.Countries[j].Country
+			}
+			return row.Countries[i].Days > row.Countries[j].Days
+		})
+
+		if _, ok := rule.(rollingWindowRule); ok {
+			row.Verdict, row.Country, row.Days = yearVerdict(row)
+		} else {
+			result, err := rule.Evaluate(data, evalDate)
+			if err != nil {
+				return nil, err
+			}
+			row.Verdict, row.Country, row.Days = result.Verdict, result.Country, result.Days
+		}
+
+		if row.UnknownDays > 0 {
+			row.Incomplete = true
+			row.Notes = append(row.Notes, fmt.Sprintf("неизвестно где %d дней", row.UnknownDays))
+		}
+		if from.Before(coveredFrom) {
+			row.Incomplete = true
+			row.Notes = append(row.Notes, fmt.Sprintf("данные начинаются с %s", utils.FormatDate(coveredFrom)))
+		}
+		if to.After(coveredTo) {
+			row.Incomplete = true
+			row.Notes = append(row.Notes, fmt.Sprintf("данные заканчиваются %s", utils.FormatDate(coveredTo)))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// yearVerdict applies the 183-day threshold to the days of the row.
+func yearVerdict(row YearRow) (Verdict, string, int) {
+	if len(row.Countries) == 0 {
+		if row.UnknownDays >= yearThreshold {
+			return VerdictInconclusive, "", 0
+		}
+		return VerdictNonResident, "", 0
+	}
+	top := row.Countries[0]
+	switch {
+	case top.Days >= yearThreshold:
+		return VerdictResident, top.Country, top.Days
+	case top.Days+row.UnknownDays >= yearThreshold:
+		return VerdictInconclusive, top.Country, top.Days
+	}
+	return VerdictNonResident, top.Country, top.Days
+}
+
+// RenderYears formats the per-year table as a Telegram message.
+func RenderYears(rows []YearRow, rule ResidencyRule) string {
+	if len(rows) == 0 {
+		return "Нет данных для анализа."
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("📆 Статус по годам (%s)\n", rule.Title()))
+	for _, row := range rows {
+		builder.WriteString(fmt.Sprintf("\n%s (%s — %s)", row.Label, utils.FormatDate(row.From), utils.FormatDate(row.To)))
+		if row.Incomplete {
+			builder.WriteString(" ⚠️ неполные данные")
+		}
+		builder.WriteString("\n")
+		for _, c := range row.Countries {
+			flag := utils.CountryToFlag(utils.CountryCodeMap[c.Country])
+			builder.WriteString(fmt.Sprintf("  %s %s: %d дней\n", flag, c.Country, c.Days))
+		}
+		if row.UnknownDays > 0 {
+			builder.WriteString(fmt.Sprintf("  🕳 Неизвестно где: %d дней\n", row.UnknownDays))
+		}
+		switch row.Verdict {
+		case VerdictResident:
+			builder.WriteString(fmt.Sprintf("  ✅ Резидент: %s\n", placeTitle(row.Country)))
+		case VerdictInconclusive:
+			builder.WriteString("  ❔ Статус не определён\n")
+		default:
+			builder.WriteString("  ⚠️ Порог не достигнут\n")
+		}
+		if len(row.Notes) > 0 {
+			builder.WriteString(fmt.Sprintf("  ℹ️ %s\n", strings.Join(row.Notes, "; ")))
+		}
+	}
+	return builder.String()
+}
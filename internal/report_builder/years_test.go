@@ -0,0 +1,66 @@
+package reportbuilder
+
+import (
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestBuildYearsReport(t *testing.T) {
+	data := model.Data{
+		Current: "30.06.2024",
+		Periods: []model.Period{
+			{In: "01.01.2022", Out: "31.12.2022", Country: "Россия"},
+			{In: "01.01.2023", Out: "30.06.2023", Country: "Грузия"},
+			{In: "11.07.2023", Out: "", Country: "Грузия"},
+		},
+	}
+	rows, err := BuildYearsReport(data, RuleByName(DefaultRule), CalendarYear)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 years, got %d", len(rows))
+	}
+	if rows[0].Label != "2022" || rows[0].Country != "Россия" || rows[0].Verdict != VerdictResident || rows[0].Incomplete {
+		t.Fatalf("unexpected 2022 row: %+v", rows[0])
+	}
+	if rows[1].Country != "Грузия" || rows[1].UnknownDays != 10 || !rows[1].Incomplete {
+		t.Fatalf("unexpected 2023 row: %+v", rows[1])
+	}
+	// вердикт строки — по дням самого года, а не по скользящим 12 месяцам
+	if !rows[2].Incomplete || rows[2].Countries[0].Days != 182 || rows[2].Verdict != VerdictNonResident {
+		t.Fatalf("unexpected 2024 row: %+v", rows[2])
+	}
+	if data.Current != "30.06.2024" {
+		t.Fatal("calculation date must not change")
+	}
+}
+
+func TestBuildYearsReportAnyTwelveMonths(t *testing.T) {
+	data := model.Data{
+		Current: "31.12.2024",
+		Periods: []model.Period{
+			{In: "01.10.2023", Out: "30.06.2024", Country: "Россия"},
+			{In: "01.07.2024", Out: "31.12.2024", Country: "Грузия"},
+		},
+	}
+	rows, err := BuildYearsReport(data, RuleByName("any_12_months_183"), CalendarYear)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// окно 12 месяцев, заканчивающееся в 2024 году, захватывает осень 2023-го
+	last := rows[len(rows)-1]
+	if last.Label != "2024" || last.Verdict != VerdictResident || last.Country != "Россия" {
+		t.Fatalf("unexpected 2024 row: %+v", last)
+	}
+}
+
+func TestYearVerdictUnknownOnly(t *testing.T) {
+	if v, _, _ := yearVerdict(YearRow{UnknownDays: 200}); v != VerdictInconclusive {
+		t.Fatalf("expected inconclusive, got %v", v)
+	}
+	if v, _, _ := yearVerdict(YearRow{UnknownDays: 20}); v != VerdictNonResident {
+		t.Fatalf("expected non-resident, got %v", v)
+	}
+}
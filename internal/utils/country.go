@@ -14,97 +14,279 @@ var CountryCodeMap = map[string]string{
 	"Австралия":            "AU",
 	"Австрия":              "AT",
 	"Азербайджан":          "AZ",
+	"Аландские острова":    "AX",
 	"Албания":              "AL",
 	"Алжир":                "DZ",
 	"Американское Самоа":   "AS",
+	"Ангилья":              "AI",
 	"Ангола":               "AO",
 	"Андорра":              "AD",
+	"Антарктида":           "AQ",
+	"Антигуа и Барбуда":    "AG",
 	"Аргентина":            "AR",
 	"Армения":              "AM",
+	"Аруба":                "AW",
 	"Афганистан":           "AF",
+	"Багамы":               "BS",
 	"Бангладеш":            "BD",
+	"Барбадос":             "BB",
+	"Бахрейн":              "BH",
 	"Беларусь":             "BY",
+	"Белиз":                "BZ",
 	"Бельгия":              "BE",
+	"Бенин":                "BJ",
+	"Бермуды":              "BM",
 	"Болгария":             "BG",
 	"Боливия":              "BO",
 	"Босния и Герцеговина": "BA",
+	"Ботсвана":             "BW",
 	"Бразилия":             "BR",
-	"Великобритания":       "GB",
-	"Венгрия":              "HU",
-	"Венесуэла":            "VE",
-	"Вьетнам":              "VN",
-	"Германия":             "DE",
-	"Гонконг":              "HK",
-	"Греция":               "GR",
-	"Грузия":               "GE",
-	"Дания":                "DK",
-	"Египет":               "EG",
-	"Израиль":              "IL",
-	"Индия":                "IN",
-	"Индонезия":            "ID",
-	"Иордания":             "JO",
-	"Ирак":                 "IQ",
-	"Иран":                 "IR",
-	"Ирландия":             "IE",
-	"Исландия":             "IS",
-	"Испания":              "ES",
-	"Италия":               "IT",
-	"Казахстан":            "KZ",
-	"Камбоджа":             "KH",
-	"Канада":               "CA",
-	"Катар":                "QA",
-	"Кения":                "KE",
-	"Кипр":                 "CY",
-	"Китай":                "CN",
-	"Колумбия":             "CO",
-	"Коста-Рика":           "CR",
-	"Куба":                 "CU",
-	"Кыргызстан":           "KG",
-	"Латвия":               "LV",
-	"Ливан":                "LB",
-	"Литва":                "LT",
-	"Люксембург":           "LU",
-	"Малайзия":             "MY",
-	"Мальта":               "MT",
-	"Марокко":              "MA",
-	"Мексика":              "MX",
-	"Молдова":              "MD",
-	"Монголия":             "MN",
-	"Нидерланды":           "NL",
-	"Новая Зеландия":       "NZ",
-	"Норвегия":             "NO",
-	"ОАЭ":                  "AE",
+	"Британская территория в Индийском океане": "IO",
+	"Британские Виргинские острова":            "VG",
+	"Бруней":                    "BN",
+	"Буркина-Фасо":              "BF",
+	"Бурунди":                   "BI",
+	"Бутан":                     "BT",
+	"Вануату":                   "VU",
+	"Ватикан":                   "VA",
+	"Великобритания":            "GB",
+	"Венгрия":                   "HU",
+	"Венесуэла":                 "VE",
+	"Виргинские острова США":    "VI",
+	"Внешние малые острова США": "UM",
+	"Восточный Тимор":           "TL",
+	"Вьетнам":                   "VN",
+	"Габон":                     "GA",
+	"Гаити":                     "HT",
+	"Гайана":                    "GY",
+	"Гамбия":                    "GM",
+	"Гана":                      "GH",
+	"Гваделупа":                 "GP",
+	"Гватемала":                 "GT",
+	"Гвинея":                    "GN",
+	"Гвинея-Бисау":              "GW",
+	"Германия":                  "DE",
+	"Гернси":                    "GG",
+	"Гибралтар":                 "GI",
+	"Гондурас":                  "HN",
+	"Гонконг":                   "HK",
+	"Гренада":                   "GD",
+	"Гренландия":                "GL",
+	"Греция":                    "GR",
+	"Грузия":                    "GE",
+	"Гуам":                      "GU",
+	"Дания":                     "DK",
+	"Джерси":                    "JE",
+	"Джибути":                   "DJ",
+	"Доминика":                  "DM",
+	"Доминиканская Республика": "DO",
+	"ДР Конго":          "CD",
+	"Египет":            "EG",
+	"Замбия":            "ZM",
+	"Западная Сахара":   "EH",
+	"Зимбабве":          "ZW",
+	"Израиль":           "IL",
+	"Индия":             "IN",
+	"Индонезия":         "ID",
+	"Иордания":          "JO",
+	"Ирак":              "IQ",
+	"Иран":              "IR",
+	"Ирландия":          "IE",
+	"Исландия":          "IS",
+	"Испания":           "ES",
+	"Италия":            "IT",
+	"Йемен":             "YE",
+	"Кабо-Верде":        "CV",
+	"Казахстан":         "KZ",
+	"Каймановы острова": "KY",
+	"Камбоджа":          "KH",
+	"Камерун":           "CM",
+	"Канада":            "CA",
+	"Карибские Нидерланды": "BQ",
+	"Катар":               "QA",
+	"Кения":               "KE",
+	"Кипр":                "CY",
+	"Кирибати":            "KI",
+	"Китай":               "CN",
+	"КНДР":                "KP",
+	"Кокосовые острова":   "CC",
+	"Колумбия":            "CO",
+	"Коморы":              "KM",
+	"Косово":              "XK",
+	"Коста-Рика":          "CR",
+	"Кот-д'Ивуар":         "CI",
+	"Куба":                "CU",
+	"Кувейт":              "KW",
+	"Кыргызстан":          "KG",
+	"Кюрасао":             "CW",
+	"Лаос":                "LA",
+	"Латвия":              "LV",
+	"Лесото":              "LS",
+	"Либерия":             "LR",
+	"Ливан":               "LB",
+	"Ливия":               "LY",
+	"Литва":               "LT",
+	"Лихтенштейн":         "LI",
+	"Люксембург":          "LU",
+	"Маврикий":            "MU",
+	"Мавритания":          "MR",
+	"Мадагаскар":          "MG",
+	"Майотта":             "YT",
+	"Макао":               "MO",
+	"Малави":              "MW",
+	"Малайзия":            "MY",
+	"Мали":                "ML",
+	"Мальдивы":            "MV",
+	"Мальта":              "MT",
+	"Марокко":             "MA",
+	"Мартиника":           "MQ",
+	"Маршалловы Острова":  "MH",
+	"Мексика":             "MX",
+	"Микронезия":          "FM",
+	"Мозамбик":            "MZ",
+	"Молдова":             "MD",
+	"Монако":              "MC",
+	"Монголия":            "MN",
+	"Монтсеррат":          "MS",
+	"Мьянма":              "MM",
+	"Намибия":             "NA",
+	"Науру":               "NR",
+	"Непал":               "NP",
+	"Нигер":               "NE",
+	"Нигерия":             "NG",
+	"Нидерланды":          "NL",
+	"Никарагуа":           "NI",
+	"Ниуэ":                "NU",
+	"Новая Зеландия":      "NZ",
+	"Новая Каледония":     "NC",
+	"Норвегия":            "NO",
+	"ОАЭ":                 "AE",
+	"Оман":                "OM",
+	"Острова Кука":        "CK",
+	"Острова Питкэрн":     "PN",
+	"Остров Мэн":          "IM",
+	"Остров Норфолк":      "NF",
+	"Остров Рождества":    "CX",
+	"Остров Святой Елены": "SH",
+	"Остров Херд и острова Макдональд": "HM",
 	"Пакистан":             "PK",
+	"Палау":                "PW",
+	"Палестина":            "PS",
 	"Панама":               "PA",
+	"Папуа — Новая Гвинея": "PG",
+	"Парагвай":             "PY",
 	"Перу":                 "PE",
 	"Польша":               "PL",
 	"Португалия":           "PT",
+	"Пуэрто-Рико":          "PR",
+	"Республика Конго":     "CG",
+	"Реюньон":              "RE",
 	"Россия":               "RU",
+	"Руанда":               "RW",
 	"Румыния":              "RO",
+	"Сальвадор":            "SV",
+	"Самоа":                "WS",
+	"Сан-Марино":           "SM",
+	"Сан-Томе и Принсипи":  "ST",
 	"Саудовская Аравия":    "SA",
-	"Сербия":               "RS",
-	"Сингапур":             "SG",
-	"Словакия":             "SK",
-	"Словения":             "SI",
-	"США":                  "US",
-	"Таджикистан":          "TJ",
-	"Таиланд":              "TH",
-	"Тунис":                "TN",
-	"Туркменистан":         "TM",
-	"Турция":               "TR",
-	"Узбекистан":           "UZ",
-	"Украина":              "UA",
-	"Филиппины":            "PH",
-	"Финляндия":            "FI",
-	"Франция":              "FR",
-	"Хорватия":             "HR",
-	"Чехия":                "CZ",
-	"Чили":                 "CL",
-	"Швейцария":            "CH",
-	"Швеция":               "SE",
-	"Шри-Ланка":            "LK",
-	"Эстония":              "EE",
-	"ЮАР":                  "ZA",
-	"Южная Корея":          "KR",
-	"Япония":               "JP",
+	"Северная Македония":   "MK",
+	"Северные Марианские острова": "MP",
+	"Сейшелы": "SC",
+	"Сенегал": "SN",
+	"Сент-Винсент и Гренадины": "VC",
+	"Сент-Китс и Невис":        "KN",
+	"Сент-Люсия":               "LC",
+	"Сен-Бартелеми":            "BL",
+	"Сен-Мартен":               "MF",
+	"Сен-Пьер и Микелон":       "PM",
+	"Сербия":                   "RS",
+	"Сингапур":                 "SG",
+	"Синт-Мартен":              "SX",
+	"Сирия":                    "SY",
+	"Словакия":                 "SK",
+	"Словения":                 "SI",
+	"Соломоновы Острова":       "SB",
+	"Сомали":                   "SO",
+	"Судан":                    "SD",
+	"Суринам":                  "SR",
+	"США":                      "US",
+	"Сьерра-Леоне":             "SL",
+	"Таджикистан":              "TJ",
+	"Таиланд":                  "TH",
+	"Тайвань":                  "TW",
+	"Танзания":                 "TZ",
+	"Тёркс и Кайкос":           "TC",
+	"Того":                     "TG",
+	"Токелау":                  "TK",
+	"Тонга":                    "TO",
+	"Тринидад и Тобаго":        "TT",
+	"Тувалу":                   "TV",
+	"Тунис":                    "TN",
+	"Туркменистан":             "TM",
+	"Турция":                   "TR",
+	"Уганда":                   "UG",
+	"Узбекистан":               "UZ",
+	"Украина":                  "UA",
+	"Уоллис и Футуна":          "WF",
+	"Уругвай":                  "UY",
+	"Фарерские острова":        "FO",
+	"Фиджи":                    "FJ",
+	"Филиппины":                "PH",
+	"Финляндия":                "FI",
+	"Фолклендские острова":     "FK",
+	"Франция":                  "FR",
+	"Французская Гвиана":       "GF",
+	"Французская Полинезия":    "PF",
+	"Французские Южные территории": "TF",
+	"Хорватия": "HR",
+	"Центральноафриканская Республика": "CF",
+	"Чад":        "TD",
+	"Черногория": "ME",
+	"Чехия":      "CZ",
+	"Чили":       "CL",
+	"Швейцария":  "CH",
+	"Швеция":     "SE",
+	"Шпицберген": "SJ",
+	"Шри-Ланка":  "LK",
+	"Эквадор":    "EC",
+	"Экваториальная Гвинея": "GQ",
+	"Эритрея":  "ER",
+	"Эсватини": "SZ",
+	"Эстония":  "EE",
+	"Эфиопия":  "ET",
+	"ЮАР":      "ZA",
+	"Южная Георгия и Южные Сандвичевы острова": "GS",
+	"Южная Корея": "KR",
+	"Южный Судан": "SS",
+	"Ямайка":      "JM",
+	"Япония":      "JP",
+}
+
+// CountryByCode returns the name used in CountryCodeMap for an ISO code.
+func CountryByCode(isoCode string) (string, bool) {
+	isoCode = strings.ToUpper(strings.TrimSpace(isoCode))
+	for name, code := range CountryCodeMap {
+		if code == isoCode {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// NormalizeCountry maps an ISO code or a differently cased name to the name
+// used in CountryCodeMap; unknown values are returned trimmed.
+func NormalizeCountry(value string) string {
+	value = strings.TrimSpace(value)
+	if _, ok := CountryCodeMap[value]; ok {
+		return value
+	}
+	if name, ok := CountryByCode(value); ok && len(value) == 2 {
+		return name
+	}
+	for name := range CountryCodeMap {
+		if strings.EqualFold(name, value) {
+			return name
+		}
+	}
+	return value
 }
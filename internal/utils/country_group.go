@@ -0,0 +1,30 @@
+package utils
+
+// CountryGroup is a set of countries whose days are summed together.
+type CountryGroup struct {
+	Name      string
+	Countries []string
+}
+
+// Contains reports whether the country belongs to the group.
+func (g CountryGroup) Contains(country string) bool {
+	for _, c := range g.Countries {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// Schengen is the Schengen area used by the 90/180 short-stay rule.
+var Schengen = CountryGroup{
+	Name: "Шенгенская зона",
+	Countries: []string{
+		"Австрия", "Бельгия", "Болгария", "Венгрия", "Германия", "Греция",
+		"Дания", "Исландия", "Испания", "Италия", "Латвия", "Литва",
+		"Лихтенштейн", "Люксембург", "Мальта", "Нидерланды", "Норвегия",
+		"Польша", "Португалия", "Румыния", "Словакия", "Словения",
+		"Финляндия", "Франция", "Хорватия", "Чехия", "Швейцария", "Швеция",
+		"Эстония",
+	},
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "testing"
+
+func TestSchengenContains(t *testing.T) {
+	if !Schengen.Contains("Германия") || Schengen.Contains("Кипр") {
+		t.Fatal("unexpected Schengen membership")
+	}
+	for _, c := range Schengen.Countries {
+		if _, ok := CountryCodeMap[c]; !ok {
+			t.Fatalf("%s is missing in CountryCodeMap", c)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CountryEnglishNames maps ISO codes of CountryCodeMap to English names.
+var CountryEnglishNames = map[string]string{
+	"AD": "Andorra", "AE": "United Arab Emirates", "AF": "Afghanistan",
+	"AG": "Antigua and Barbuda", "AI": "Anguilla", "AL": "Albania", "AM": "Armenia",
+	"AO": "Angola", "AQ": "Antarctica", "AR": "Argentina", "AS": "American Samoa",
+	"AT": "Austria", "AU": "Australia", "AW": "Aruba", "AX": "Aland Islands",
+	"AZ": "Azerbaijan", "BA": "Bosnia and Herzegovina", "BB": "Barbados",
+	"BD": "Bangladesh", "BE": "Belgium", "BF": "Burkina Faso", "BG": "Bulgaria",
+	"BH": "Bahrain", "BI": "Burundi", "BJ": "Benin", "BL": "Saint Barthelemy",
+	"BM": "Bermuda", "BN": "Brunei", "BO": "Bolivia", "BQ": "Caribbean Netherlands",
+	"BR": "Brazil", "BS": "Bahamas", "BT": "Bhutan", "BW": "Botswana", "BY": "Belarus",
+	"BZ": "Belize", "CA": "Canada", "CC": "Cocos Islands", "CD": "DR Congo",
+	"CF": "Central African Republic", "CG": "Republic of the Congo", "CH": "Switzerland",
+	"CI": "Cote d'Ivoire", "CK": "Cook Islands", "CL": "Chile", "CM": "Cameroon",
+	"CN": "China", "CO": "Colombia", "CR": "Costa Rica", "CU": "Cuba", "CV": "Cape Verde",
+	"CW": "Curacao", "CX": "Christmas Island", "CY": "Cyprus", "CZ": "Czech Republic",
+	"DE": "Germany", "DJ": "Djibouti", "DK": "Denmark", "DM": "Dominica",
+	"DO": "Dominican Republic", "DZ": "Algeria", "EC": "Ecuador", "EE": "Estonia",
+	"EG": "Egypt", "EH": "Western Sahara", "ER": "Eritrea", "ES": "Spain", "ET": "Ethiopia",
+	"FI": "Finland", "FJ": "Fiji", "FK": "Falkland Islands", "FM": "Micronesia",
+	"FO": "Faroe Islands", "FR": "France", "GA": "Gabon", "GB": "United Kingdom",
+	"GD": "Grenada", "GE": "Georgia", "GF": "French Guiana", "GG": "Guernsey",
+	"GH": "Ghana", "GI": "Gibraltar", "GL": "Greenland", "GM": "Gambia", "GN": "Guinea",
+	"GP": "Guadeloupe", "GQ": "Equatorial Guinea", "GR": "Greece",
+	"GS": "South Georgia and the South Sandwich Islands", "GT": "Guatemala", "GU": "Guam",
+	"GW": "Guinea-Bissau", "GY": "Guyana", "HK": "Hong Kong",
+	"HM": "Heard Island and McDonald Islands", "HN": "Honduras", "HR": "Croatia",
+	"HT": "Haiti", "HU": "Hungary", "ID": "Indonesia", "IE": "Ireland", "IL": "Israel",
+	"IM": "Isle of Man", "IN": "India", "IO": "British Indian Ocean Territory",
+	"IQ": "Iraq", "IR": "Iran", "IS": "Iceland", "IT": "Italy", "JE": "Jersey",
+	"JM": "Jamaica", "JO": "Jordan", "JP": "Japan", "KE": "Kenya", "KG": "Kyrgyzstan",
+	"KH": "Cambodia", "KI": "Kiribati", "KM": "Comoros", "KN": "Saint Kitts and Nevis",
+	"KP": "North Korea", "KR": "South Korea", "KW": "Kuwait", "KY": "Cayman Islands",
+	"KZ": "Kazakhstan", "LA": "Laos", "LB": "Lebanon", "LC": "Saint Lucia",
+	"LI": "Liechtenstein", "LK": "Sri Lanka", "LR": "Liberia", "LS": "Lesotho",
+	"LT": "Lithuania", "LU": "Luxembourg", "LV": "Latvia", "LY": "Libya", "MA": "Morocco",
+	"MC": "Monaco", "MD": "Moldova", "ME": "Montenegro", "MF": "Saint Martin",
+	"MG": "Madagascar", "MH": "Marshall Islands", "MK": "North Macedonia", "ML": "Mali",
+	"MM": "Myanmar", "MN": "Mongolia", "MO": "Macau", "MP": "Northern Mariana Islands",
+	"MQ": "Martinique", "MR": "Mauritania", "MS": "Montserrat", "MT": "Malta",
+	"MU": "Mauritius", "MV": "Maldives", "MW": "Malawi", "MX": "Mexico", "MY": "Malaysia",
+	"MZ": "Mozambique", "NA": "Namibia", "NC": "New Caledonia", "NE": "Niger",
+	"NF": "Norfolk Island", "NG": "Nigeria", "NI": "Nicaragua", "NL": "Netherlands",
+	"NO": "Norway", "NP": "Nepal", "NR": "Nauru", "NU": "Niue", "NZ": "New Zealand",
+	"OM": "Oman", "PA": "Panama", "PE": "Peru", "PF": "French Polynesia",
+	"PG": "Papua New Guinea", "PH": "Philippines", "PK": "Pakistan", "PL": "Poland",
+	"PM": "Saint Pierre and Miquelon", "PN": "Pitcairn Islands", "PR": "Puerto Rico",
+	"PS": "Palestine", "PT": "Portugal", "PW": "Palau", "PY": "Paraguay", "QA": "Qatar",
+	"RE": "Reunion", "RO": "Romania", "RS": "Serbia", "RU": "Russia", "RW": "Rwanda",
+	"SA": "Saudi Arabia", "SB": "Solomon Islands", "SC": "Seychelles", "SD": "Sudan",
+	"SE": "Sweden", "SG": "Singapore", "SH": "Saint Helena", "SI": "Slovenia",
+	"SJ": "Svalbard", "SK": "Slovakia", "SL": "Sierra Leone", "SM": "San Marino",
+	"SN": "Senegal", "SO": "Somalia", "SR": "Suriname", "SS": "South Sudan",
+	"ST": "Sao Tome and Principe", "SV": "El Salvador", "SX": "Sint Maarten", "SY": "Syria",
+	"SZ": "Eswatini", "TC": "Turks and Caicos Islands", "TD": "Chad",
+	"TF": "French Southern Territories", "TG": "Togo", "TH": "Thailand", "TJ": "Tajikistan",
+	"TK": "Tokelau", "TL": "Timor-Leste", "TM": "Turkmenistan", "TN": "Tunisia",
+	"TO": "Tonga", "TR": "Turkey", "TT": "Trinidad and Tobago", "TV": "Tuvalu",
+	"TW": "Taiwan", "TZ": "Tanzania", "UA": "Ukraine", "UG": "Uganda",
+	"UM": "United States Minor Outlying Islands", "US": "United States", "UY": "Uruguay",
+	"UZ": "Uzbekistan", "VA": "Vatican City", "VC": "Saint Vincent and the Grenadines",
+	"VE": "Venezuela", "VG": "British Virgin Islands", "VI": "US Virgin Islands",
+	"VN": "Vietnam", "VU": "Vanuatu", "WF": "Wallis and Futuna", "WS": "Samoa",
+	"XK": "Kosovo", "YE": "Yemen", "YT": "Mayotte", "ZA": "South Africa", "ZM": "Zambia",
+	"ZW": "Zimbabwe",
+}
+
+// countryAliases are common alternative English names and the official
+// Russian names used in government documents.
+var countryAliases = map[string]string{
+	"USA": "US", "UK": "GB", "UAE": "AE", "England": "GB", "Czechia": "CZ",
+	"Türkiye": "TR", "Turkiye": "TR", "Holland": "NL", "Korea": "KR",
+	"Объединенные Арабские Эмираты": "AE", "Объединённые Арабские Эмираты": "AE",
+	"Соединенные Штаты Америки": "US", "Соединённые Штаты Америки": "US",
+	"Соединенное Королевство": "GB", "Соединённое Королевство": "GB",
+	"Республика Корея": "KR", "Киргизия": "KG", "Киргизская Республика": "KG",
+	"Белоруссия": "BY", "Молдавия": "MD", "Китайская Народная Республика": "CN",
+	"Чешская Республика": "CZ", "Турецкая Республика": "TR", "Южно-Африканская Республика": "ZA",
+	"Македония": "MK", "Доминикана": "DO", "Кот-д’Ивуар": "CI", "Свазиленд": "SZ",
+	"Macedonia": "MK", "Ivory Coast": "CI", "Burma": "MM", "East Timor": "TL", "Swaziland": "SZ",
+}
+
+// ambiguousNames are country names that are also common words or personal
+// names ("того", "чад", New Jersey); FindCountry does not look for them in
+// free text.
+var ambiguousNames = map[string]bool{
+	"Того": true, "Чад": true, "Мали": true, "Бутан": true, "Белиз": true, "Самоа": true,
+	"Доминика": true, "Джерси": true, "Сальвадор": true, "Chad": true, "Jersey": true,
+}
+
+// maxInflection is how many letters a Russian case ending may add to a name.
+const maxInflection = 2
+
+// FindCountry looks for a country mentioned in free text, such as a
+// calendar event "Отпуск в Грузии" or "Tbilisi, Georgia", and returns its
+// CountryCodeMap name. Russian names match in any case form. When several
+// countries are mentioned, the first one wins.
+func FindCountry(text string) (string, bool) {
+	lower := []rune(strings.ToLower(text))
+	best, bestPos, bestLen := "", -1, 0
+	try := func(country, pattern string, inflected bool) {
+		needle := []rune(strings.ToLower(pattern))
+		for pos := indexRunes(lower, needle, 0); pos >= 0; pos = indexRunes(lower, needle, pos+1) {
+			if pos > 0 && unicode.IsLetter(lower[pos-1]) {
+				continue
+			}
+			tail := 0
+			for end := pos + len(needle); end < len(lower) && unicode.IsLetter(lower[end]); end++ {
+				tail++
+			}
+			if tail > 0 && (!inflected || tail > maxInflection) {
+				continue
+			}
+			if bestPos < 0 || pos < bestPos || (pos == bestPos && len(needle) > bestLen) {
+				best, bestPos, bestLen = country, pos, len(needle)
+			}
+			return
+		}
+	}
+	for name, code := range CountryCodeMap {
+		if !ambiguousNames[name] {
+			try(name, russianStem(name), true)
+		}
+		if english, ok := CountryEnglishNames[code]; ok && !ambiguousNames[english] {
+			try(name, english, false)
+		}
+	}
+	for alias, code := range countryAliases {
+		if name, ok := CountryByCode(code); ok {
+			try(name, alias, false)
+		}
+	}
+	return best, bestPos >= 0
+}
+
+// russianStem drops the ending that changes with the grammatical case,
+// e.g. "Грузия" → "Грузи" to match "Грузии" and "Грузию".
+func russianStem(name string) string {
+	runes := []rune(name)
+	if len(runes) > 4 && strings.ContainsRune("аяйь", runes[len(runes)-1]) {
+		return string(runes[:len(runes)-1])
+	}
+	return name
+}
+
+func indexRunes(s, sub []rune, from int) int {
+	for i := from; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
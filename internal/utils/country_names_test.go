@@ -0,0 +1,35 @@
+package utils
+
+import "testing"
+
+func TestFindCountry(t *testing.T) {
+	tests := map[string]string{
+		"Отпуск в Грузии":  "Грузия",
+		"Tbilisi, Georgia": "Грузия",
+		"Командировка: Казахстан, Алматы": "Казахстан",
+		"Trip to the USA": "США",
+		"Лечу в Турцию, потом в Грузию": "Турция",
+		"Индивидуальная консультация":   "",
+		"Georgian restaurant":           "",
+		"Встреча с коллегами":           "",
+		"ОБЪЕДИНЕННЫЕ АРАБСКИЕ ЭМИРАТЫ": "ОАЭ",
+		"Республика Корея":              "Южная Корея",
+		"Конференция в Нью-Джерси":      "",
+		"После того — в Черногорию":     "Черногория",
+		"Skopje, North Macedonia":       "Северная Македония",
+	}
+	for text, want := range tests {
+		got, ok := FindCountry(text)
+		if got != want || ok != (want != "") {
+			t.Errorf("FindCountry(%q) = %q, %v; want %q", text, got, ok, want)
+		}
+	}
+}
+
+func TestCountryEnglishNamesCoverCodes(t *testing.T) {
+	for name, code := range CountryCodeMap {
+		if _, ok := CountryEnglishNames[code]; !ok {
+			t.Errorf("no English name for %s (%s)", name, code)
+		}
+	}
+}
@@ -13,3 +13,18 @@ func TestCountryCodeMap(t *testing.T) {
 		t.Fatalf("wrong code")
 	}
 }
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := map[string]string{
+		"GE":        "Грузия",
+		"ru":        "Россия",
+		" Россия ":  "Россия",
+		"грузия":    "Грузия",
+		"Атлантида": "Атлантида",
+	}
+	for in, want := range tests {
+		if got := NormalizeCountry(in); got != want {
+			t.Errorf("NormalizeCountry(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
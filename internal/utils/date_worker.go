@@ -1,6 +1,10 @@
 package utils
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 func ParseDate(dateStr string) (time.Time, error) {
 	return time.Parse("02.01.2006", dateStr)
@@ -9,3 +13,25 @@ func ParseDate(dateStr string) (time.Time, error) {
 func FormatDate(d time.Time) string {
 	return d.Format("02.01.2006")
 }
+
+// dateLayouts are the formats ParseAnyDate accepts, day before month.
+var dateLayouts = []string{
+	"02.01.2006", "2.1.2006", "02.01.06", "2.1.06",
+	"2006-01-02", "02-01-2006", "2-1-2006",
+	"02/01/2006", "2/1/2006", "2006/01/02", "2006.01.02",
+}
+
+// ParseAnyDate parses a date typed by hand or exported from a spreadsheet;
+// a time of day after the date is ignored.
+func ParseAnyDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexAny(value, " T"); i > 0 {
+		value = value[:i]
+	}
+	for _, layout := range dateLayouts {
+		if d, err := time.Parse(layout, value); err == nil {
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неизвестный формат даты «%s»", value)
+}
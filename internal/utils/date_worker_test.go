@@ -17,3 +17,15 @@ func TestParseDateInvalid(t *testing.T) {
 		t.Fatal("expected error for invalid date")
 	}
 }
+
+func TestParseAnyDate(t *testing.T) {
+	for _, value := range []string{"05.03.2024", "5.3.2024", "05.03.24", "2024-03-05", "05/03/2024", "2024-03-05T10:00:00Z", "05.03.2024 10:00"} {
+		d, err := ParseAnyDate(value)
+		if err != nil || FormatDate(d) != "05.03.2024" {
+			t.Errorf("ParseAnyDate(%q) = %v, %v", value, d, err)
+		}
+	}
+	if _, err := ParseAnyDate("March 5"); err == nil {
+		t.Fatal("expected error for an unknown format")
+	}
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"time"
+	// встроенная база часовых поясов: в контейнере может не быть zoneinfo
+	_ "time/tzdata"
+)
+
+// ParseDateTime returns the instant of a local date and "15:04" clock time
+// in the IANA zone; an empty zone means UTC.
+func ParseDateTime(date, clock, zone string) (time.Time, error) {
+	loc := time.UTC
+	if zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.ParseInLocation("02.01.2006 15:04", date+" "+clock, loc)
+}
+
+// DayNumber returns the number of the calendar date of t, as seen in the
+// location of t, counted from 01.01.1970.
+func DayNumber(t time.Time) int {
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return int(date.Unix() / 86400)
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateTime(t *testing.T) {
+	moscow, err := ParseDateTime("01.03.2024", "23:30", "Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := moscow.UTC().Format("02.01.2006 15:04"); got != "01.03.2024 20:30" {
+		t.Fatalf("unexpected UTC time %s", got)
+	}
+	if _, err := ParseDateTime("01.03.2024", "23:30", "Mars/Olympus"); err == nil {
+		t.Fatal("expected error for unknown zone")
+	}
+	if _, err := ParseDateTime("01.03.2024", "25:00", ""); err == nil {
+		t.Fatal("expected error for invalid time")
+	}
+}
+
+func TestDayNumberAcrossDST(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/London")
+	from := time.Date(2024, time.March, 30, 0, 0, 0, 0, loc)
+	to := time.Date(2024, time.April, 1, 0, 0, 0, 0, loc)
+	if got := DayNumber(to) - DayNumber(from); got != 2 {
+		t.Fatalf("expected 2 days, got %d", got)
+	}
+}
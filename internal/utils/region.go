@@ -0,0 +1,61 @@
+package utils
+
+import "strings"
+
+// Region is a sub-national jurisdiction such as a US state.
+type Region struct {
+	Country string
+	Code    string
+	Name    string
+	// Aliases are other accepted spellings, compared case-insensitively.
+	Aliases []string
+}
+
+// Regions lists the jurisdictions the bot recognises by name.
+var Regions = []Region{
+	{"США", "NY", "Нью-Йорк", []string{"New York", "NY", "Нью Йорк"}},
+	{"США", "CA", "Калифорния", []string{"California", "CA"}},
+	{"США", "FL", "Флорида", []string{"Florida", "FL"}},
+	{"США", "TX", "Техас", []string{"Texas", "TX"}},
+	{"США", "WA", "Вашингтон", []string{"Washington", "WA"}},
+	{"США", "NJ", "Нью-Джерси", []string{"New Jersey", "NJ"}},
+	{"США", "MA", "Массачусетс", []string{"Massachusetts", "MA"}},
+	{"США", "IL", "Иллинойс", []string{"Illinois", "IL"}},
+	{"Канада", "ON", "Онтарио", []string{"Ontario", "ON"}},
+	{"Канада", "QC", "Квебек", []string{"Quebec", "Québec", "QC"}},
+	{"Канада", "BC", "Британская Колумбия", []string{"British Columbia", "BC"}},
+	{"Канада", "AB", "Альберта", []string{"Alberta", "AB"}},
+	{"Швейцария", "ZH", "Цюрих", []string{"Zürich", "Zurich", "ZH"}},
+	{"Швейцария", "GE", "Женева", []string{"Genève", "Geneva", "GE"}},
+	{"Швейцария", "VD", "Во", []string{"Vaud", "VD"}},
+	{"Швейцария", "BE", "Берн", []string{"Bern", "BE"}},
+	{"Швейцария", "ZG", "Цуг", []string{"Zug", "ZG"}},
+}
+
+// FindRegion looks up a region of the country by name, code or alias.
+func FindRegion(country, name string) (Region, bool) {
+	name = strings.TrimSpace(name)
+	for _, r := range Regions {
+		if r.Country != country {
+			continue
+		}
+		if strings.EqualFold(r.Name, name) || strings.EqualFold(r.Code, name) {
+			return r, true
+		}
+		for _, a := range r.Aliases {
+			if strings.EqualFold(a, name) {
+				return r, true
+			}
+		}
+	}
+	return Region{}, false
+}
+
+// RegionName returns the canonical name of a known region or the trimmed
+// input otherwise.
+func RegionName(country, name string) string {
+	if r, ok := FindRegion(country, name); ok {
+		return r.Name
+	}
+	return strings.TrimSpace(name)
+}
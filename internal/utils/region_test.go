@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestFindRegion(t *testing.T) {
+	tests := []struct {
+		country, name, want string
+	}{
+		{"США", "new york", "NY"},
+		{"США", "Калифорния", "CA"},
+		{"Швейцария", "zurich", "ZH"},
+		{"Канада", "NY", ""},
+	}
+	for _, tt := range tests {
+		r, _ := FindRegion(tt.country, tt.name)
+		if r.Code != tt.want {
+			t.Fatalf("%s/%s: expected %q, got %q", tt.country, tt.name, tt.want, r.Code)
+		}
+	}
+	if got := RegionName("США", " Орегон "); got != "Орегон" {
+		t.Fatalf("expected raw name, got %q", got)
+	}
+}
@@ -0,0 +1,122 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// Codes of problems in the border crossing log.
+const (
+	InvalidDirection Code = "invalid_direction"
+	MissingExit      Code = "missing_exit"
+	MissingEntry     Code = "missing_entry"
+	DoubleEntry      Code = "double_entry"
+	DoubleExit       Code = "double_exit"
+)
+
+// crossingEvent is a crossing with its parsed date and 1-based number.
+type crossingEvent struct {
+	model.Crossing
+	n    int
+	date time.Time
+}
+
+// SortCrossings orders the log by date. On the same day a crossing with a
+// time goes by time; without times exits come before entries.
+func SortCrossings(crossings []model.Crossing) {
+	sort.SliceStable(crossings, func(i, j int) bool {
+		return crossingBefore(crossings[i], crossings[j])
+	})
+}
+
+func crossingBefore(a, b model.Crossing) bool {
+	da, errA := utils.ParseDate(a.Date)
+	db, errB := utils.ParseDate(b.Date)
+	if errA != nil || errB != nil || !da.Equal(db) {
+		return errA == nil && errB == nil && da.Before(db)
+	}
+	if a.Time != "" && b.Time != "" && a.Time != b.Time {
+		return a.Time < b.Time
+	}
+	return a.Direction == model.DirectionExit && b.Direction == model.DirectionEntry
+}
+
+// DeriveCrossings turns the crossing log into periods: a stay runs from an
+// entry to the next exit from the same country. A log starting with an exit
+// gives an open start, one ending with an entry an open end. Gaps between an
+// exit and the next entry stay unknown. Missing and repeated crossings are
+// reported and resolved as the messages say.
+func DeriveCrossings(crossings []model.Crossing) ([]model.Period, []Issue) {
+	var issues []Issue
+	add := func(code Code, severity Severity, n int, format string, args ...any) {
+		issues = append(issues, Issue{Code: code, Severity: severity, Crossing: n, Message: fmt.Sprintf(format, args...)})
+	}
+
+	events := make([]crossingEvent, 0, len(crossings))
+	for i, c := range crossings {
+		date, err := utils.ParseDate(c.Date)
+		if err != nil {
+			add(InvalidDate, Error, i+1, "некорректная дата «%s»", c.Date)
+			continue
+		}
+		if c.Direction != model.DirectionEntry && c.Direction != model.DirectionExit {
+			add(InvalidDirection, Error, i+1, "неизвестное направление «%s»", c.Direction)
+			continue
+		}
+		events = append(events, crossingEvent{Crossing: c, n: i + 1, date: date})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return crossingBefore(events[i].Crossing, events[j].Crossing) })
+
+	var periods []model.Period
+	var open *model.Period
+	openN := 0
+	var last *crossingEvent
+	for i := range events {
+		e := &events[i]
+		switch e.Direction {
+		case model.DirectionEntry:
+			if open != nil {
+				if open.Country == e.Country {
+					add(DoubleEntry, Warning, e.n, "повторный въезд в %s %s без выезда после въезда %s — не учитывается", e.Country, e.Date, open.In)
+					continue
+				}
+				add(MissingExit, Warning, openN, "нет выезда из %s до въезда в %s %s — выезд считается в тот же день", open.Country, e.Country, e.Date)
+				open.Out, open.OutTime = e.Date, ""
+				periods = append(periods, *open)
+			}
+			open = &model.Period{In: e.Date, InTime: e.Time, Country: e.Country}
+			openN = e.n
+		case model.DirectionExit:
+			switch {
+			case open != nil && open.Country == e.Country:
+				open.Out, open.OutTime = e.Date, e.Time
+				periods = append(periods, *open)
+				open = nil
+			case open != nil:
+				add(MissingExit, Warning, openN, "нет выезда из %s до выезда из %s %s — выезд считается в тот же день", open.Country, e.Country, e.Date)
+				add(MissingEntry, Warning, e.n, "нет въезда в %s до выезда %s — дни в стране не учтены", e.Country, e.Date)
+				open.Out, open.OutTime = e.Date, ""
+				periods = append(periods, *open)
+				open = nil
+			case last != nil && last.Direction == model.DirectionExit && last.Country == e.Country:
+				add(DoubleExit, Warning, e.n, "повторный выезд из %s %s без въезда после выезда %s — не учитывается", e.Country, e.Date, last.Date)
+				continue
+			default:
+				p := model.Period{Out: e.Date, OutTime: e.Time, Country: e.Country}
+				if last != nil {
+					add(MissingEntry, Warning, e.n, "нет въезда в %s до выезда %s — пребывание считается с %s, даты предыдущего пересечения", e.Country, e.Date, last.Date)
+					p.In = last.Date
+				}
+				periods = append(periods, p)
+			}
+		}
+		last = e
+	}
+	if open != nil {
+		periods = append(periods, *open)
+	}
+	return periods, issues
+}
@@ -0,0 +1,125 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func entry(date, country string) model.Crossing {
+	return model.Crossing{Date: date, Direction: model.DirectionEntry, Country: country}
+}
+
+func exit(date, country string) model.Crossing {
+	return model.Crossing{Date: date, Direction: model.DirectionExit, Country: country}
+}
+
+func TestDeriveCrossings(t *testing.T) {
+	crossings := []model.Crossing{
+		exit("01.02.2024", "Россия"),
+		// в тот же день без времени выезд идёт раньше въезда
+		entry("01.02.2024", "Грузия"),
+		{Date: "15.03.2024", Time: "23:10", Direction: model.DirectionExit, Country: "Грузия", Checkpoint: "Верхний Ларс"},
+		entry("16.03.2024", "Россия"),
+	}
+	periods, issues := DeriveCrossings(crossings)
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+	want := []model.Period{
+		{Out: "01.02.2024", Country: "Россия"},
+		{In: "01.02.2024", Out: "15.03.2024", OutTime: "23:10", Country: "Грузия"},
+		{In: "16.03.2024", Country: "Россия"},
+	}
+	if !reflect.DeepEqual(periods, want) {
+		t.Errorf("got %+v, want %+v", periods, want)
+	}
+}
+
+func TestDeriveCrossingsIssues(t *testing.T) {
+	tests := []struct {
+		name      string
+		crossings []model.Crossing
+		code      Code
+		crossing  int
+		periods   []model.Period
+	}{
+		{
+			name:      "missing exit",
+			crossings: []model.Crossing{entry("01.02.2024", "Грузия"), entry("10.02.2024", "Армения"), exit("20.02.2024", "Армения")},
+			code:      MissingExit,
+			crossing:  1,
+			periods: []model.Period{
+				{In: "01.02.2024", Out: "10.02.2024", Country: "Грузия"},
+				{In: "10.02.2024", Out: "20.02.2024", Country: "Армения"},
+			},
+		},
+		{
+			name:      "double entry",
+			crossings: []model.Crossing{entry("01.02.2024", "Грузия"), entry("05.02.2024", "Грузия"), exit("20.02.2024", "Грузия")},
+			code:      DoubleEntry,
+			crossing:  2,
+			periods:   []model.Period{{In: "01.02.2024", Out: "20.02.2024", Country: "Грузия"}},
+		},
+		{
+			name:      "double exit",
+			crossings: []model.Crossing{exit("01.02.2024", "Россия"), exit("01.02.2024", "Россия"), entry("20.02.2024", "Россия")},
+			code:      DoubleExit,
+			crossing:  2,
+			periods:   []model.Period{{Out: "01.02.2024", Country: "Россия"}, {In: "20.02.2024", Country: "Россия"}},
+		},
+		{
+			name:      "missing entry",
+			crossings: []model.Crossing{exit("01.02.2024", "Россия"), exit("20.02.2024", "Грузия")},
+			code:      MissingEntry,
+			crossing:  2,
+			periods:   []model.Period{{Out: "01.02.2024", Country: "Россия"}, {In: "01.02.2024", Out: "20.02.2024", Country: "Грузия"}},
+		},
+		{
+			name:      "invalid date",
+			crossings: []model.Crossing{entry("32.01.2024", "Грузия")},
+			code:      InvalidDate,
+			crossing:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods, issues := DeriveCrossings(tt.crossings)
+			found := false
+			for _, issue := range issues {
+				if issue.Code == tt.code && issue.Crossing == tt.crossing {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected %s at crossing %d, got %+v", tt.code, tt.crossing, issues)
+			}
+			if !reflect.DeepEqual(periods, tt.periods) {
+				t.Errorf("got %+v, want %+v", periods, tt.periods)
+			}
+		})
+	}
+}
+
+func TestValidateIncludesCrossings(t *testing.T) {
+	data := model.Data{Crossings: []model.Crossing{entry("01.02.2024", "Грузия"), entry("05.02.2024", "Грузия")}}
+	data.Periods, _ = DeriveCrossings(data.Crossings)
+	text := Render(validate(data, today))
+	if !strings.Contains(text, "Пересечение 2: повторный въезд") {
+		t.Errorf("crossing issue not rendered:\n%s", text)
+	}
+}
+
+func TestSortCrossings(t *testing.T) {
+	crossings := []model.Crossing{
+		entry("02.01.2024", "Грузия"),
+		{Date: "01.01.2024", Time: "20:00", Direction: model.DirectionEntry, Country: "Россия"},
+		{Date: "01.01.2024", Time: "08:00", Direction: model.DirectionExit, Country: "Грузия"},
+	}
+	SortCrossings(crossings)
+	if crossings[0].Time != "08:00" || crossings[1].Time != "20:00" || crossings[2].Date != "02.01.2024" {
+		t.Errorf("wrong order %+v", crossings)
+	}
+}
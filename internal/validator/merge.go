@@ -0,0 +1,198 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// Conflict is an imported period that overlaps existing ones or another
+// period of the same import.
+type Conflict struct {
+	Period model.Period
+	// With lists the 1-based numbers of the overlapped existing periods.
+	With []int
+	// WithImported lists the overlapped periods of the import that are added.
+	WithImported []model.Period
+}
+
+// Trim closes an open-ended existing period at the first later arrival of
+// the import.
+type Trim struct {
+	// Index is the 1-based number of the existing period.
+	Index  int
+	Period model.Period
+	Out    string
+}
+
+// MergeResult is the diff between the current periods and an import.
+type MergeResult struct {
+	Added      []model.Period
+	Duplicates []model.Period
+	Conflicts  []Conflict
+	Trimmed    []Trim
+	existing   []model.Period
+}
+
+// Merge compares imported periods with the existing ones and with each
+// other. An imported period equal to an existing or an earlier imported one
+// is a duplicate; one overlapping existing periods or an added period of the
+// same import (nested calendar events) by more than a travel day is a
+// conflict; the rest are added. An
+// open-ended existing period, usually the current stay, is closed at the
+// first imported arrival after it instead of conflicting with every trip.
+func Merge(existing, incoming []model.Period) (MergeResult, error) {
+	result := MergeResult{existing: existing}
+	current, err := parseItems(existing)
+	if err != nil {
+		return result, err
+	}
+	imported, err := parseItems(incoming)
+	if err != nil {
+		return result, fmt.Errorf("в загружаемом файле: %w", err)
+	}
+
+	for i := range current {
+		if current[i].period.Out != "" {
+			continue
+		}
+		var end time.Time
+		for _, it := range imported {
+			if it.in.After(current[i].in) && (end.IsZero() || it.in.Before(end)) {
+				end = it.in
+			}
+		}
+		if end.IsZero() {
+			continue
+		}
+		current[i].out = end
+		result.Trimmed = append(result.Trimmed, Trim{Index: i + 1, Period: current[i].period, Out: utils.FormatDate(end)})
+	}
+
+	var added []item
+	for _, it := range imported {
+		duplicate := false
+		var with []int
+		var withImported []model.Period
+		for i, cur := range current {
+			if samePeriod(it.period, cur.period) {
+				duplicate = true
+				break
+			}
+			if it.in.Before(cur.out) && cur.in.Before(it.out) {
+				with = append(with, i+1)
+			}
+		}
+		for _, prev := range added {
+			if samePeriod(it.period, prev.period) {
+				duplicate = true
+				break
+			}
+			if it.in.Before(prev.out) && prev.in.Before(it.out) {
+				withImported = append(withImported, prev.period)
+			}
+		}
+		switch {
+		case duplicate:
+			result.Duplicates = append(result.Duplicates, it.period)
+		case len(with) > 0 || len(withImported) > 0:
+			result.Conflicts = append(result.Conflicts, Conflict{Period: it.period, With: with, WithImported: withImported})
+		default:
+			added = append(added, it)
+			result.Added = append(result.Added, it.period)
+		}
+	}
+	return result, nil
+}
+
+// IsEmpty reports whether the import brings nothing new.
+func (m MergeResult) IsEmpty() bool {
+	return len(m.Added) == 0 && len(m.Conflicts) == 0
+}
+
+// Periods returns the merged list sorted by arrival; conflicting periods are
+// included only when asked to.
+func (m MergeResult) Periods(withConflicts bool) []model.Period {
+	periods := append([]model.Period{}, m.existing...)
+	for _, t := range m.Trimmed {
+		periods[t.Index-1].Out, periods[t.Index-1].OutTime = t.Out, ""
+	}
+	periods = append(periods, m.Added...)
+	if withConflicts {
+		for _, c := range m.Conflicts {
+			periods = append(periods, c.Period)
+		}
+	}
+	items, err := parseItems(periods)
+	if err != nil {
+		return periods
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].in.Before(items[j].in) })
+	for i, it := range items {
+		periods[i] = it.period
+	}
+	return periods
+}
+
+// Render formats the diff as a Telegram message.
+func (m MergeResult) Render() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("📥 Новых периодов: %d, уже есть: %d, конфликтуют: %d\n", len(m.Added), len(m.Duplicates), len(m.Conflicts)))
+	for _, t := range m.Trimmed {
+		builder.WriteString(fmt.Sprintf("✂️ %s закончится %s — в день первого нового въезда\n", describe(t.Period), t.Out))
+	}
+	for _, p := range m.Added {
+		builder.WriteString(fmt.Sprintf("➕ %s\n", describe(p)))
+	}
+	for _, p := range m.Duplicates {
+		builder.WriteString(fmt.Sprintf("🟰 %s\n", describe(p)))
+	}
+	for _, c := range m.Conflicts {
+		var parts []string
+		if len(c.With) > 0 {
+			numbers := make([]string, 0, len(c.With))
+			for _, n := range c.With {
+				numbers = append(numbers, strconv.Itoa(n))
+			}
+			parts = append(parts, "периодами "+strings.Join(numbers, ", "))
+		}
+		for _, p := range c.WithImported {
+			parts = append(parts, "новым периодом "+describe(p))
+		}
+		builder.WriteString(fmt.Sprintf("❗ %s пересекается с %s\n", describe(c.Period), strings.Join(parts, " и ")))
+	}
+	return builder.String()
+}
+
+func parseItems(periods []model.Period) ([]item, error) {
+	items := make([]item, 0, len(periods))
+	for i, p := range periods {
+		it := item{period: p, out: openEnd}
+		var err error
+		if p.In != "" {
+			if it.in, err = utils.ParseDate(p.In); err != nil {
+				return nil, fmt.Errorf("некорректная дата въезда «%s» (период %d)", p.In, i+1)
+			}
+		}
+		if p.Out != "" {
+			if it.out, err = utils.ParseDate(p.Out); err != nil {
+				return nil, fmt.Errorf("некорректная дата выезда «%s» (период %d)", p.Out, i+1)
+			}
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+// samePeriod compares the fields that identify a stay.
+func samePeriod(a, b model.Period) bool {
+	return a.In == b.In && a.Out == b.Out && a.Country == b.Country && a.Region == b.Region
+}
+
+func describe(p model.Period) string {
+	return item{period: p}.String()
+}
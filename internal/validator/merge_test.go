@@ -0,0 +1,128 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func TestMerge(t *testing.T) {
+	existing := []model.Period{
+		{In: "01.01.2024", Out: "31.03.2024", Country: "Россия"},
+		{In: "31.03.2024", Out: "30.04.2024", Country: "Грузия"},
+	}
+	incoming := []model.Period{
+		{In: "31.03.2024", Out: "30.04.2024", Country: "Грузия"},
+		{In: "30.04.2024", Out: "31.05.2024", Country: "Армения"},
+		{In: "15.03.2024", Out: "20.03.2024", Country: "Турция"},
+		{Out: "31.12.2023", Country: "Казахстан"},
+	}
+	result, err := Merge(existing, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added) != 2 || len(result.Duplicates) != 1 || len(result.Conflicts) != 1 {
+		t.Fatalf("unexpected diff %+v", result)
+	}
+	if c := result.Conflicts[0]; c.Period.Country != "Турция" || !reflect.DeepEqual(c.With, []int{1}) {
+		t.Fatalf("unexpected conflict %+v", c)
+	}
+
+	want := []string{"Казахстан", "Россия", "Грузия", "Армения"}
+	var got []string
+	for _, p := range result.Periods(false) {
+		got = append(got, p.Country)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v", got)
+	}
+	if n := len(result.Periods(true)); n != 5 {
+		t.Fatalf("expected conflicts to be included, got %d periods", n)
+	}
+	if !strings.Contains(result.Render(), "❗ Турция 15.03.2024 — 20.03.2024 пересекается с периодами 1") {
+		t.Fatalf("unexpected render:\n%s", result.Render())
+	}
+}
+
+func TestMergeOpenEndedLast(t *testing.T) {
+	existing := []model.Period{
+		{In: "01.01.2024", Out: "31.03.2024", Country: "Россия"},
+		{In: "31.03.2024", Country: "Грузия"},
+	}
+	incoming := []model.Period{
+		{In: "10.05.2024", Out: "20.05.2024", Country: "Турция"},
+		{In: "20.05.2024", Out: "30.06.2024", Country: "Армения"},
+	}
+	result, err := Merge(existing, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added) != 2 || len(result.Conflicts) != 0 {
+		t.Fatalf("open current stay must not conflict: %+v", result)
+	}
+	if len(result.Trimmed) != 1 || result.Trimmed[0].Index != 2 || result.Trimmed[0].Out != "10.05.2024" {
+		t.Fatalf("unexpected trims %+v", result.Trimmed)
+	}
+	want := []model.Period{
+		{In: "01.01.2024", Out: "31.03.2024", Country: "Россия"},
+		{In: "31.03.2024", Out: "10.05.2024", Country: "Грузия"},
+		{In: "10.05.2024", Out: "20.05.2024", Country: "Турция"},
+		{In: "20.05.2024", Out: "30.06.2024", Country: "Армения"},
+	}
+	if got := result.Periods(true); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v", got)
+	}
+	if issues := Validate(model.Data{Periods: result.Periods(true), Current: "30.06.2024"}); HasErrors(issues) {
+		t.Fatalf("merged periods must be valid: %v", issues)
+	}
+	if !strings.Contains(result.Render(), "✂️ Грузия 31.03.2024 — … закончится 10.05.2024") {
+		t.Fatalf("unexpected render:\n%s", result.Render())
+	}
+	if existing[1].Out != "" {
+		t.Fatal("existing periods must not change")
+	}
+}
+
+func TestMergeNothingNew(t *testing.T) {
+	existing := []model.Period{{In: "01.01.2024", Out: "31.03.2024", Country: "Россия"}}
+	result, err := Merge(existing, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsEmpty() {
+		t.Fatalf("expected nothing new, got %+v", result)
+	}
+}
+
+func TestMergeInvalidDate(t *testing.T) {
+	if _, err := Merge(nil, []model.Period{{In: "32.01.2024", Country: "Россия"}}); err == nil {
+		t.Fatal("expected an error for an unparseable date")
+	}
+}
+
+func TestMergeOverlapInsideImport(t *testing.T) {
+	incoming := []model.Period{
+		{In: "01.03.2024", Out: "20.03.2024", Country: "Германия"},
+		{In: "05.03.2024", Out: "06.03.2024", Country: "Германия"},
+		{In: "01.03.2024", Out: "20.03.2024", Country: "Германия"},
+		{In: "20.03.2024", Out: "25.03.2024", Country: "Австрия"},
+	}
+	result, err := Merge(nil, incoming)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Added) != 2 || len(result.Duplicates) != 1 || len(result.Conflicts) != 1 {
+		t.Fatalf("unexpected diff %+v", result)
+	}
+	if c := result.Conflicts[0]; c.Period.In != "05.03.2024" || len(c.WithImported) != 1 || len(c.With) != 0 {
+		t.Fatalf("unexpected conflict %+v", c)
+	}
+	if issues := Validate(model.Data{Current: "31.03.2024", Periods: result.Periods(false)}); HasErrors(issues) {
+		t.Fatalf("merged periods are invalid: %s", Render(issues))
+	}
+	if !strings.Contains(result.Render(), "пересекается с новым периодом Германия 01.03.2024 — 20.03.2024") {
+		t.Fatalf("unexpected render:\n%s", result.Render())
+	}
+}
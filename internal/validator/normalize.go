@@ -0,0 +1,213 @@
+package validator
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// OverlapRule decides which of two overlapping periods keeps the days.
+type OverlapRule string
+
+const (
+	// KeepLater trims the earlier period; a trip nested inside a longer
+	// period splits it in two.
+	KeepLater OverlapRule = "later"
+	// KeepEarlier trims the later period; a nested period is dropped.
+	KeepEarlier OverlapRule = "earlier"
+)
+
+// OverlapRules lists the rules with their titles in display order.
+var OverlapRules = []struct {
+	Rule  OverlapRule
+	Title string
+}{
+	{KeepLater, "Приоритет у более позднего периода"},
+	{KeepEarlier, "Приоритет у более раннего периода"},
+}
+
+// Change describes one step of the normalisation.
+type Change struct {
+	Kind    ChangeKind
+	Message string
+}
+
+// ChangeKind identifies what the normalisation did.
+type ChangeKind string
+
+const (
+	Sorted    ChangeKind = "sorted"
+	Merged    ChangeKind = "merged"
+	Trimmed   ChangeKind = "trimmed"
+	Split     ChangeKind = "split"
+	Dropped   ChangeKind = "dropped"
+	GapFilled ChangeKind = "gap_filled"
+)
+
+// openEnd stands for a period without a departure date.
+var openEnd = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// item is a period with parsed dates; an open start is the zero time.
+type item struct {
+	period  model.Period
+	in, out time.Time
+}
+
+func (it item) String() string {
+	in, out := it.period.In, it.period.Out
+	if in == "" {
+		in = "…"
+	}
+	if out == "" {
+		out = "…"
+	}
+	return fmt.Sprintf("%s %s — %s", it.period.Country, in, out)
+}
+
+func (it *item) setIn(date time.Time) {
+	it.in = date
+	it.period.In = utils.FormatDate(date)
+	it.period.InTime = ""
+}
+
+func (it *item) setOut(date time.Time) {
+	it.out = date
+	it.period.Out = utils.FormatDate(date)
+	it.period.OutTime = ""
+}
+
+// Normalize sorts the periods by arrival, trims overlaps by the rule, makes
+// the gaps between periods explicit "unknown" periods and merges adjacent
+// periods in the same place. Periods with unparseable dates make it fail.
+// A touching departure and arrival day is a travel day, not an overlap.
+func Normalize(data model.Data, rule OverlapRule) (model.Data, []Change, error) {
+	items, err := parseItems(data.Periods)
+	if err != nil {
+		return data, nil, err
+	}
+	for i, it := range items {
+		if it.out.Before(it.in) {
+			return data, nil, fmt.Errorf("дата выезда раньше даты въезда (период %d)", i+1)
+		}
+	}
+
+	var changes []Change
+	note := func(kind ChangeKind, format string, args ...any) {
+		changes = append(changes, Change{Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !sort.SliceIsSorted(items, func(i, j int) bool { return items[i].in.Before(items[j].in) }) {
+		sort.SliceStable(items, func(i, j int) bool { return items[i].in.Before(items[j].in) })
+		note(Sorted, "периоды упорядочены по дате въезда")
+	}
+
+	items = trimOverlaps(items, rule, note)
+	items = fillGaps(items, note)
+	items = mergeAdjacent(items, note)
+
+	periods := make([]model.Period, 0, len(items))
+	for _, it := range items {
+		periods = append(periods, it.period)
+	}
+	data.Periods = periods
+	return data, changes, nil
+}
+
+func trimOverlaps(items []item, rule OverlapRule, note func(ChangeKind, string, ...any)) []item {
+	var result []item
+	for _, cur := range items {
+		if len(result) == 0 {
+			result = append(result, cur)
+			continue
+		}
+		prev := &result[len(result)-1]
+		if !cur.in.Before(prev.out) {
+			result = append(result, cur)
+			continue
+		}
+		switch rule {
+		case KeepEarlier:
+			if !cur.out.After(prev.out) {
+				note(Dropped, "период %s удалён: целиком внутри периода %s", cur, prev)
+				continue
+			}
+			was := cur.String()
+			cur.setIn(prev.out)
+			note(Trimmed, "период %s начинается с %s, после периода %s", was, cur.period.In, prev)
+			result = append(result, cur)
+		default:
+			was := prev.String()
+			head := prev.in.Before(cur.in)
+			tail := prev.out.After(cur.out)
+			rest := *prev
+			if tail {
+				rest.setIn(cur.out)
+			}
+			switch {
+			case head && tail:
+				prev.setOut(cur.in)
+				note(Split, "период %s разделён поездкой %s", was, cur)
+				result = append(result, cur, rest)
+			case head:
+				prev.setOut(cur.in)
+				note(Trimmed, "период %s заканчивается %s, до периода %s", was, prev.period.Out, cur)
+				result = append(result, cur)
+			case tail:
+				note(Trimmed, "период %s начинается с %s, после периода %s", was, rest.period.In, cur)
+				result[len(result)-1] = cur
+				result = append(result, rest)
+			default:
+				note(Dropped, "период %s удалён: целиком внутри периода %s", was, cur)
+				result[len(result)-1] = cur
+			}
+		}
+	}
+	return result
+}
+
+func fillGaps(items []item, note func(ChangeKind, string, ...any)) []item {
+	var result []item
+	for i, cur := range items {
+		if i > 0 {
+			prev := items[i-1]
+			if gapStart := prev.out.AddDate(0, 0, 1); gapStart.Before(cur.in) {
+				gap := item{period: model.Period{Country: unknownCountry}}
+				gap.setIn(gapStart)
+				gap.setOut(cur.in.AddDate(0, 0, -1))
+				note(GapFilled, "добавлен период «неизвестно где» %s — %s", gap.period.In, gap.period.Out)
+				result = append(result, gap)
+			}
+		}
+		result = append(result, cur)
+	}
+	return result
+}
+
+// samePlace reports whether two periods can be merged into one.
+func samePlace(a, b model.Period) bool {
+	return a.Country == b.Country && a.Region == b.Region && a.Purpose == b.Purpose &&
+		a.TimeZone == b.TimeZone && slices.Equal(a.Flags, b.Flags)
+}
+
+func mergeAdjacent(items []item, note func(ChangeKind, string, ...any)) []item {
+	var result []item
+	for _, cur := range items {
+		if len(result) > 0 {
+			prev := &result[len(result)-1]
+			if samePlace(prev.period, cur.period) && !cur.in.After(prev.out.AddDate(0, 0, 1)) {
+				was := prev.String()
+				if cur.out.After(prev.out) {
+					prev.out = cur.out
+					prev.period.Out, prev.period.OutTime = cur.period.Out, cur.period.OutTime
+				}
+				note(Merged, "периоды %s и %s объединены", was, cur)
+				continue
+			}
+		}
+		result = append(result, cur)
+	}
+	return result
+}
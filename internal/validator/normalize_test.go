@@ -0,0 +1,110 @@
+package validator
+
+import (
+	"reflect"
+	"testing"
+
+	"telegram-tax-bot/internal/model"
+)
+
+func periodsOf(data model.Data) [][3]string {
+	var out [][3]string
+	for _, p := range data.Periods {
+		out = append(out, [3]string{p.In, p.Out, p.Country})
+	}
+	return out
+}
+
+func kinds(changes []Change) map[ChangeKind]bool {
+	found := make(map[ChangeKind]bool)
+	for _, c := range changes {
+		found[c.Kind] = true
+	}
+	return found
+}
+
+func TestNormalizeSortsMergesAndFillsGaps(t *testing.T) {
+	data := model.Data{Periods: []model.Period{
+		{In: "01.06.2024", Out: "30.06.2024", Country: "Грузия"},
+		{In: "01.01.2024", Out: "31.03.2024", Country: "Россия"},
+		{In: "31.03.2024", Out: "20.05.2024", Country: "Россия"},
+	}}
+	got, changes, err := Normalize(data, KeepLater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]string{
+		{"01.01.2024", "20.05.2024", "Россия"},
+		{"21.05.2024", "31.05.2024", "unknown"},
+		{"01.06.2024", "30.06.2024", "Грузия"},
+	}
+	if !reflect.DeepEqual(periodsOf(got), want) {
+		t.Fatalf("got %v", periodsOf(got))
+	}
+	if k := kinds(changes); !k[Sorted] || !k[Merged] || !k[GapFilled] {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	if issues := validate(got, today); HasErrors(issues) {
+		t.Fatalf("normalised data must be valid, got %+v", issues)
+	}
+}
+
+func TestNormalizeOverlapRules(t *testing.T) {
+	data := model.Data{Periods: []model.Period{
+		{In: "01.01.2024", Out: "31.05.2024", Country: "Россия"},
+		{In: "01.03.2024", Out: "10.03.2024", Country: "Турция"},
+		{In: "20.05.2024", Out: "30.06.2024", Country: "Грузия"},
+	}}
+	tests := []struct {
+		rule OverlapRule
+		want [][3]string
+		kind ChangeKind
+	}{
+		{KeepLater, [][3]string{
+			{"01.01.2024", "01.03.2024", "Россия"},
+			{"01.03.2024", "10.03.2024", "Турция"},
+			{"10.03.2024", "20.05.2024", "Россия"},
+			{"20.05.2024", "30.06.2024", "Грузия"},
+		}, Split},
+		{KeepEarlier, [][3]string{
+			{"01.01.2024", "31.05.2024", "Россия"},
+			{"31.05.2024", "30.06.2024", "Грузия"},
+		}, Dropped},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.rule), func(t *testing.T) {
+			got, changes, err := Normalize(data, tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(periodsOf(got), tt.want) {
+				t.Fatalf("got %v", periodsOf(got))
+			}
+			if !kinds(changes)[tt.kind] {
+				t.Fatalf("expected %s in %+v", tt.kind, changes)
+			}
+		})
+	}
+}
+
+func TestNormalizeKeepsOpenEnds(t *testing.T) {
+	data := model.Data{Periods: []model.Period{
+		{In: "01.03.2024", Country: "Грузия"},
+		{Out: "29.02.2024", Country: "Россия"},
+	}}
+	got, _, err := Normalize(data, KeepLater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]string{{"", "29.02.2024", "Россия"}, {"01.03.2024", "", "Грузия"}}
+	if !reflect.DeepEqual(periodsOf(got), want) {
+		t.Fatalf("got %v", periodsOf(got))
+	}
+}
+
+func TestNormalizeInvalidDate(t *testing.T) {
+	data := model.Data{Periods: []model.Period{{In: "31.02.2024", Out: "01.03.2024", Country: "Россия"}}}
+	if _, _, err := Normalize(data, KeepLater); err == nil {
+		t.Fatal("expected an error for an unparseable date")
+	}
+}
@@ -0,0 +1,187 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+	"telegram-tax-bot/internal/model"
+	"telegram-tax-bot/internal/utils"
+	"time"
+)
+
+// Code identifies the kind of problem found in the data.
+type Code string
+
+const (
+	InvalidDate    Code = "invalid_date"
+	InvalidTime    Code = "invalid_time"
+	OutBeforeIn    Code = "out_before_in"
+	Overlap        Code = "overlap"
+	OutOfOrder     Code = "out_of_order"
+	OpenMiddle     Code = "open_middle"
+	UnknownCountry Code = "unknown_country"
+	FutureDate     Code = "future_date"
+)
+
+// Severity tells whether the report can still be built.
+type Severity string
+
+const (
+	// Error makes the report wrong or impossible to build.
+	Error Severity = "error"
+	// Warning is suspicious but does not prevent the calculation.
+	Warning Severity = "warning"
+)
+
+// Issue is a problem found in the data.
+type Issue struct {
+	Code     Code
+	Severity Severity
+	// Period is the 1-based number of the period, 0 for the whole data.
+	Period int
+	// Crossing is the 1-based number of a border crossing, if any.
+	Crossing int
+	Message  string
+}
+
+// unknownCountry marks gaps entered as periods.
+const unknownCountry = "unknown"
+
+// Validate checks the periods and the calculation date.
+func Validate(data model.Data) []Issue {
+	now := time.Now().UTC()
+	return validate(data, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+func validate(data model.Data, today time.Time) []Issue {
+	var issues []Issue
+	add := func(code Code, severity Severity, period int, format string, args ...any) {
+		issues = append(issues, Issue{Code: code, Severity: severity, Period: period, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if data.Current != "" {
+		if _, err := utils.ParseDate(data.Current); err != nil {
+			add(InvalidDate, Error, 0, "некорректная дата расчёта «%s»", data.Current)
+		}
+	}
+	if len(data.Crossings) > 0 {
+		_, crossingIssues := DeriveCrossings(data.Crossings)
+		issues = append(issues, crossingIssues...)
+	}
+
+	type parsed struct {
+		in, out           time.Time
+		inOK, outOK       bool
+		arrival, departed time.Time
+	}
+	var previous *parsed
+	last := len(data.Periods) - 1
+
+	for i, p := range data.Periods {
+		n := i + 1
+		cur := parsed{}
+		var err error
+
+		if p.In != "" {
+			if cur.in, err = utils.ParseDate(p.In); err != nil {
+				add(InvalidDate, Error, n, "некорректная дата въезда «%s»", p.In)
+			} else {
+				cur.inOK = true
+			}
+		} else if i > 0 {
+			add(OpenMiddle, Error, n, "нет даты въезда — она может отсутствовать только у первого периода")
+		}
+		if p.Out != "" {
+			if cur.out, err = utils.ParseDate(p.Out); err != nil {
+				add(InvalidDate, Error, n, "некорректная дата выезда «%s»", p.Out)
+			} else {
+				cur.outOK = true
+			}
+		} else if i < last {
+			add(OpenMiddle, Error, n, "нет даты выезда — она может отсутствовать только у последнего периода")
+		}
+
+		if cur.inOK && p.InTime != "" {
+			if cur.arrival, err = utils.ParseDateTime(p.In, p.InTime, p.TimeZone); err != nil {
+				add(InvalidTime, Error, n, "некорректное время въезда «%s» или часовой пояс «%s»", p.InTime, p.TimeZone)
+			}
+		}
+		if cur.outOK && p.OutTime != "" {
+			if cur.departed, err = utils.ParseDateTime(p.Out, p.OutTime, p.TimeZone); err != nil {
+				add(InvalidTime, Error, n, "некорректное время выезда «%s» или часовой пояс «%s»", p.OutTime, p.TimeZone)
+			}
+		}
+
+		if cur.inOK && cur.outOK && cur.out.Before(cur.in) {
+			add(OutBeforeIn, Error, n, "дата выезда %s раньше даты въезда %s", p.Out, p.In)
+		}
+
+		country := strings.TrimSpace(p.Country)
+		switch {
+		case country == "":
+			add(UnknownCountry, Error, n, "не указана страна")
+		case country == unknownCountry:
+		default:
+			if _, ok := utils.CountryCodeMap[country]; !ok {
+				add(UnknownCountry, Warning, n, "неизвестная страна «%s» — проверьте написание", p.Country)
+			}
+		}
+
+		for _, d := range []struct {
+			date time.Time
+			ok   bool
+			raw  string
+		}{{cur.in, cur.inOK, p.In}, {cur.out, cur.outOK, p.Out}} {
+			if d.ok && d.date.After(today) {
+				add(FutureDate, Warning, n, "дата %s в будущем", d.raw)
+			}
+		}
+
+		if previous != nil && cur.inOK {
+			switch {
+			case previous.inOK && cur.in.Before(previous.in):
+				add(OutOfOrder, Error, n, "период начинается раньше предыдущего — периоды не по порядку")
+			case previous.outOK && cur.in.Before(previous.out):
+				// перелёт через линию перемены дат: моменты идут по порядку
+				if cur.arrival.IsZero() || previous.departed.IsZero() || cur.arrival.Before(previous.departed) {
+					add(Overlap, Error, n, "пересекается с периодом %d: въезд %s раньше выезда %s", n-1, p.In, data.Periods[i-1].Out)
+				}
+			}
+		}
+		previous = &cur
+	}
+	return issues
+}
+
+// HasErrors reports whether any issue is an error.
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Render formats the issues as a Telegram message.
+func Render(issues []Issue) string {
+	if len(issues) == 0 {
+		return ""
+	}
+	builder := strings.Builder{}
+	builder.WriteString("🔎 Проверка данных:\n")
+	for _, issue := range issues {
+		icon := "⛔"
+		if issue.Severity == Warning {
+			icon = "⚠️"
+		}
+		switch {
+		case issue.Crossing > 0:
+			builder.WriteString(fmt.Sprintf("%s Пересечение %d: %s\n", icon, issue.Crossing, issue.Message))
+		case issue.Period > 0:
+			builder.WriteString(fmt.Sprintf("%s Период %d: %s\n", icon, issue.Period, issue.Message))
+		default:
+			builder.WriteString(fmt.Sprintf("%s %s\n", icon, issue.Message))
+		}
+	}
+	return builder.String()
+}
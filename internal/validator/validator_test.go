@@ -0,0 +1,101 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"telegram-tax-bot/internal/model"
+)
+
+var today = time.Date(2024, time.June, 30, 0, 0, 0, 0, time.UTC)
+
+func codes(issues []Issue) map[Code]int {
+	found := make(map[Code]int)
+	for _, issue := range issues {
+		found[issue.Code] = issue.Period
+	}
+	return found
+}
+
+func TestValidateClean(t *testing.T) {
+	data := model.Data{
+		Current: "30.06.2024",
+		Periods: []model.Period{
+			{Out: "31.01.2024", Country: "Россия"},
+			{In: "01.02.2024", Out: "31.03.2024", Country: "Грузия"},
+			{In: "01.04.2024", Country: "unknown"},
+		},
+	}
+	if issues := validate(data, today); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", issues)
+	}
+}
+
+func TestValidateIssues(t *testing.T) {
+	tests := []struct {
+		name     string
+		periods  []model.Period
+		code     Code
+		period   int
+		severity Severity
+	}{
+		{"invalid date", []model.Period{{In: "31.02.2024", Out: "01.03.2024", Country: "Россия"}}, InvalidDate, 1, Error},
+		{"invalid time", []model.Period{{In: "01.01.2024", InTime: "25:00", Out: "01.03.2024", Country: "Россия"}}, InvalidTime, 1, Error},
+		{"out before in", []model.Period{{In: "10.03.2024", Out: "01.03.2024", Country: "Россия"}}, OutBeforeIn, 1, Error},
+		{"overlap", []model.Period{
+			{In: "01.01.2024", Out: "10.03.2024", Country: "Россия"},
+			{In: "01.03.2024", Out: "01.04.2024", Country: "Грузия"},
+		}, Overlap, 2, Error},
+		{"out of order", []model.Period{
+			{In: "01.03.2024", Out: "01.04.2024", Country: "Россия"},
+			{In: "01.01.2024", Out: "01.02.2024", Country: "Грузия"},
+		}, OutOfOrder, 2, Error},
+		{"open middle", []model.Period{
+			{In: "01.01.2024", Country: "Россия"},
+			{In: "01.03.2024", Out: "01.04.2024", Country: "Грузия"},
+		}, OpenMiddle, 1, Error},
+		{"unknown country", []model.Period{{In: "01.01.2024", Out: "01.03.2024", Country: "Нарния"}}, UnknownCountry, 1, Warning},
+		{"future date", []model.Period{{In: "01.01.2024", Out: "01.08.2024", Country: "Россия"}}, FutureDate, 1, Warning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validate(model.Data{Periods: tt.periods}, today)
+			period, ok := codes(issues)[tt.code]
+			if !ok || period != tt.period {
+				t.Fatalf("expected %s for period %d, got %+v", tt.code, tt.period, issues)
+			}
+			if HasErrors(issues) != (tt.severity == Error) {
+				t.Fatalf("unexpected severity in %+v", issues)
+			}
+		})
+	}
+}
+
+func TestValidateDateLine(t *testing.T) {
+	// вылет из Окленда 02.01 в 10:00, прилёт в Гонолулу 01.01 в 20:00 того же дня по UTC
+	data := model.Data{Periods: []model.Period{
+		{In: "01.12.2023", Out: "02.01.2024", OutTime: "10:00", TimeZone: "Pacific/Auckland", Country: "Новая Зеландия"},
+		{In: "01.01.2024", InTime: "20:00", TimeZone: "Pacific/Honolulu", Out: "01.02.2024", Country: "США"},
+	}}
+	if _, ok := codes(validate(data, today))[Overlap]; ok {
+		t.Fatal("ordered instants across the date line must not be reported as overlap")
+	}
+}
+
+func TestValidateCalcDate(t *testing.T) {
+	issues := validate(model.Data{Current: "2024-06-30"}, today)
+	if len(issues) != 1 || issues[0].Code != InvalidDate || issues[0].Period != 0 {
+		t.Fatalf("expected invalid calculation date, got %+v", issues)
+	}
+}
+
+func TestRender(t *testing.T) {
+	if Render(nil) != "" {
+		t.Fatal("no issues must render as empty string")
+	}
+	got := Render([]Issue{{Code: UnknownCountry, Severity: Warning, Period: 2, Message: "неизвестная страна"}})
+	if !strings.Contains(got, "⚠️ Период 2: неизвестная страна") {
+		t.Fatalf("unexpected render: %s", got)
+	}
+}